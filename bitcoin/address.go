@@ -0,0 +1,243 @@
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	Mainnet = "mainnet"
+	Testnet = "testnet"
+	Regtest = "regtest"
+
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	bech32Charset  = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	bech32Constant  = 1
+	bech32mConstant = 0x2bc830a3
+)
+
+var (
+	ErrInvalidChecksum = fmt.Errorf("invalid checksum")
+	ErrInvalidNetwork  = fmt.Errorf("address does not belong to the network")
+)
+
+type networkParams struct {
+	pubKeyHashPrefix byte
+	scriptHashPrefix byte
+	bech32HRP        string
+}
+
+var networks = map[string]networkParams{
+	Mainnet: {pubKeyHashPrefix: 0x00, scriptHashPrefix: 0x05, bech32HRP: "bc"},
+	Testnet: {pubKeyHashPrefix: 0x6f, scriptHashPrefix: 0xc4, bech32HRP: "tb"},
+	Regtest: {pubKeyHashPrefix: 0x6f, scriptHashPrefix: 0xc4, bech32HRP: "bcrt"},
+}
+
+// Address represents a validated address together with the script paying to it.
+type Address struct {
+	String string
+	Script []byte
+}
+
+// IsValidNetwork: returns true if the network is supported
+func IsValidNetwork(network string) bool {
+	_, ok := networks[network]
+	return ok
+}
+
+// DecodeAddress: validates a base58check (P2PKH or P2SH) or bech32/bech32m (segwit) address for the given network
+func DecodeAddress(address string, network string) (*Address, error) {
+	params, ok := networks[network]
+	if !ok {
+		return nil, fmt.Errorf("unknown network: %s", network)
+	}
+
+	var script []byte
+	var err error
+	if hrp := segwitHRP(address); hrp != "" {
+		if hrp != params.bech32HRP {
+			return nil, ErrInvalidNetwork
+		}
+		script, err = decodeSegwitAddress(address, params.bech32HRP)
+	} else {
+		script, err = decodeBase58Address(address, params)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Address{
+		String: address,
+		Script: script,
+	}, nil
+}
+
+// segwitHRP: returns the human readable part if the address belongs to any known network
+func segwitHRP(address string) string {
+	address = strings.ToLower(address)
+	for _, params := range networks {
+		if strings.HasPrefix(address, params.bech32HRP+"1") {
+			return params.bech32HRP
+		}
+	}
+	return ""
+}
+
+func decodeBase58Address(address string, params networkParams) ([]byte, error) {
+	decoded, err := base58Decode(address)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) != 25 {
+		return nil, fmt.Errorf("invalid address length")
+	}
+
+	payload, checksum := decoded[:21], decoded[21:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return nil, ErrInvalidChecksum
+	}
+
+	hash := payload[1:]
+	switch payload[0] {
+	case params.pubKeyHashPrefix:
+		// OP_DUP OP_HASH160 <hash> OP_EQUALVERIFY OP_CHECKSIG
+		return append(append([]byte{0x76, 0xa9, 0x14}, hash...), 0x88, 0xac), nil
+	case params.scriptHashPrefix:
+		// OP_HASH160 <hash> OP_EQUAL
+		return append(append([]byte{0xa9, 0x14}, hash...), 0x87), nil
+	default:
+		return nil, ErrInvalidNetwork
+	}
+}
+
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range s {
+		i := strings.IndexRune(base58Alphabet, c)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid base58 character: %q", c)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(i)))
+	}
+
+	// leading 1s represent leading zero bytes
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+func decodeSegwitAddress(address string, hrp string) ([]byte, error) {
+	if address != strings.ToLower(address) && address != strings.ToUpper(address) {
+		return nil, fmt.Errorf("mixed case address")
+	}
+	address = strings.ToLower(address)
+	if len(address) > 90 {
+		return nil, fmt.Errorf("invalid address length")
+	}
+
+	separator := strings.LastIndex(address, "1")
+	if separator < 1 || separator+7 > len(address) || address[:separator] != hrp {
+		return nil, ErrInvalidNetwork
+	}
+
+	data := make([]byte, 0, len(address)-separator-1)
+	for _, c := range address[separator+1:] {
+		i := strings.IndexRune(bech32Charset, c)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid bech32 character: %q", c)
+		}
+		data = append(data, byte(i))
+	}
+
+	constant := bech32Polymod(append(bech32HRPExpand(hrp), data...))
+	data = data[:len(data)-6]
+	if len(data) == 0 {
+		return nil, fmt.Errorf("missing witness version")
+	}
+
+	// witness v0 uses bech32 (BIP173), while v1+ use bech32m (BIP350)
+	version := data[0]
+	if (version == 0 && constant != bech32Constant) || (version != 0 && constant != bech32mConstant) {
+		return nil, ErrInvalidChecksum
+	}
+	if version > 16 {
+		return nil, fmt.Errorf("invalid witness version: %d", version)
+	}
+
+	program, err := convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return nil, fmt.Errorf("invalid witness program length: %d", len(program))
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return nil, fmt.Errorf("invalid witness v0 program length: %d", len(program))
+	}
+
+	opcode := byte(0x00)
+	if version > 0 {
+		opcode = 0x50 + version
+	}
+	return append([]byte{opcode, byte(len(program))}, program...), nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	r := make([]byte, 0, len(hrp)*2+1)
+	for _, c := range hrp {
+		r = append(r, byte(c>>5))
+	}
+	r = append(r, 0)
+	for _, c := range hrp {
+		r = append(r, byte(c&31))
+	}
+	return r
+}
+
+func convertBits(data []byte, from uint, to uint, pad bool) ([]byte, error) {
+	acc := uint32(0)
+	bits := uint(0)
+	maxv := uint32(1)<<to - 1
+	r := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, v := range data {
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			r = append(r, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			r = append(r, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	return r, nil
+}
@@ -0,0 +1,84 @@
+package bitcoin
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeAddress(t *testing.T) {
+	tests := []struct {
+		name           string
+		address        string
+		network        string
+		expectedScript string
+		expectedError  bool
+	}{
+		{
+			name:           "mainnet P2PKH",
+			address:        "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH",
+			network:        Mainnet,
+			expectedScript: "76a914751e76e8199196d454941c45d1b3a323f1433bd688ac",
+		},
+		{
+			name:           "mainnet P2SH",
+			address:        "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+			network:        Mainnet,
+			expectedScript: "a914b472a266d0bd89c13706a4132ccfb16f7c3b9fcb87",
+		},
+		{
+			name:           "mainnet P2WPKH",
+			address:        "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+			network:        Mainnet,
+			expectedScript: "0014751e76e8199196d454941c45d1b3a323f1433bd6",
+		},
+		{
+			name:           "mainnet P2TR",
+			address:        "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+			network:        Mainnet,
+			expectedScript: "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		},
+		{
+			name:           "testnet P2WSH",
+			address:        "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7",
+			network:        Testnet,
+			expectedScript: "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+		},
+		{
+			name:          "error with mainnet address on testnet",
+			address:       "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+			network:       Testnet,
+			expectedError: true,
+		},
+		{
+			name:          "error with bech32 checksum for witness v1",
+			address:       "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd",
+			network:       Mainnet,
+			expectedError: true,
+		},
+		{
+			name:          "error with invalid base58 checksum",
+			address:       "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLz",
+			network:       Mainnet,
+			expectedError: true,
+		},
+		{
+			name:          "error with mixed case",
+			address:       "bc1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+			network:       Mainnet,
+			expectedError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, err := DecodeAddress(tt.address, tt.network)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScript, hex.EncodeToString(address.Script))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package bitcoin
+
+import "crypto/sha256"
+
+// DoubleSHA256: returns sha256(sha256(b))
+func DoubleSHA256(b []byte) []byte {
+	first := sha256.Sum256(b)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+// ReverseBytes: returns a reversed copy of b
+func ReverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+// MerkleRootFromBranch: computes the merkle root starting from the coinbase hash and the branch sent in mining.notify
+func MerkleRootFromBranch(coinbaseHash []byte, branch [][]byte) []byte {
+	root := coinbaseHash
+	for _, h := range branch {
+		root = DoubleSHA256(append(append([]byte{}, root...), h...))
+	}
+	return root
+}
+
+// MerkleBranch: returns the hashes needed to compute the merkle root from the coinbase hash, given the
+// rest of the transaction hashes in internal byte order
+func MerkleBranch(txHashes [][]byte) [][]byte {
+	branch := [][]byte{}
+	// first position is reserved for the coinbase, which is unknown
+	level := append([][]byte{nil}, txHashes...)
+	for len(level) > 1 {
+		branch = append(branch, level[1])
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		next := [][]byte{nil}
+		for i := 2; i < len(level); i += 2 {
+			next = append(next, DoubleSHA256(append(append([]byte{}, level[i]...), level[i+1]...)))
+		}
+		level = next
+	}
+	return branch
+}
@@ -0,0 +1,57 @@
+package bitcoin
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	HeaderSize = 80
+)
+
+// BlockHeader represents the 80 bytes block header. Hashes are kept in internal byte order.
+type BlockHeader struct {
+	Version    uint32
+	PrevHash   []byte
+	MerkleRoot []byte
+	Time       uint32
+	Bits       uint32
+	Nonce      uint32
+}
+
+// Serialize: returns the header as it's hashed and sent over the wire
+func (h *BlockHeader) Serialize() ([]byte, error) {
+	if len(h.PrevHash) != 32 || len(h.MerkleRoot) != 32 {
+		return nil, fmt.Errorf("invalid header hashes length")
+	}
+
+	b := make([]byte, HeaderSize)
+	binary.LittleEndian.PutUint32(b[0:4], h.Version)
+	copy(b[4:36], h.PrevHash)
+	copy(b[36:68], h.MerkleRoot)
+	binary.LittleEndian.PutUint32(b[68:72], h.Time)
+	binary.LittleEndian.PutUint32(b[72:76], h.Bits)
+	binary.LittleEndian.PutUint32(b[76:80], h.Nonce)
+	return b, nil
+}
+
+// Hash: returns the double SHA256 of the serialized header in internal byte order
+func (h *BlockHeader) Hash() ([]byte, error) {
+	b, err := h.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	return DoubleSHA256(b), nil
+}
+
+// SwapWords: swaps the byte order of every 4 bytes word, which is how Stratum encodes the previous block hash
+func SwapWords(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := 0; i+4 <= len(b); i += 4 {
+		r[i] = b[i+3]
+		r[i+1] = b[i+2]
+		r[i+2] = b[i+1]
+		r[i+3] = b[i]
+	}
+	return r
+}
@@ -0,0 +1,43 @@
+package bitcoin
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockHeader_Hash(t *testing.T) {
+	merkleRoot, _ := hex.DecodeString("4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b")
+
+	// genesis block
+	h := &BlockHeader{
+		Version:    1,
+		PrevHash:   make([]byte, 32),
+		MerkleRoot: ReverseBytes(merkleRoot),
+		Time:       1231006505,
+		Bits:       0x1d00ffff,
+		Nonce:      2083236893,
+	}
+
+	hash, err := h.Hash()
+	assert.NoError(t, err)
+	assert.Equal(t, "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", hex.EncodeToString(ReverseBytes(hash)))
+	assert.True(t, HashToBig(hash).Cmp(CompactToTarget(h.Bits)) <= 0)
+}
+
+func TestDifficultyToTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		difficulty float64
+	}{
+		{name: "difficulty 1", difficulty: 1},
+		{name: "difficulty 1024", difficulty: 1024},
+		{name: "difficulty lower than 1", difficulty: 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.difficulty, TargetToDifficulty(DifficultyToTarget(tt.difficulty)), 1e-9)
+		})
+	}
+}
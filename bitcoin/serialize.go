@@ -0,0 +1,61 @@
+package bitcoin
+
+import (
+	"encoding/binary"
+)
+
+// VarInt: returns the compact size encoding of n
+func VarInt(n uint64) []byte {
+	switch {
+	case n < 0xfd:
+		return []byte{byte(n)}
+	case n <= 0xffff:
+		b := make([]byte, 3)
+		b[0] = 0xfd
+		binary.LittleEndian.PutUint16(b[1:], uint16(n))
+		return b
+	case n <= 0xffffffff:
+		b := make([]byte, 5)
+		b[0] = 0xfe
+		binary.LittleEndian.PutUint32(b[1:], uint32(n))
+		return b
+	default:
+		b := make([]byte, 9)
+		b[0] = 0xff
+		binary.LittleEndian.PutUint64(b[1:], n)
+		return b
+	}
+}
+
+// ScriptNumPush: returns the minimal script push of n, as required by BIP34 for the block height
+func ScriptNumPush(n int64) []byte {
+	if n == 0 {
+		return []byte{0x00}
+	}
+	if n > 0 && n <= 16 {
+		return []byte{0x50 + byte(n)}
+	}
+
+	var num []byte
+	negative := n < 0
+	abs := n
+	if negative {
+		abs = -n
+	}
+	for abs > 0 {
+		num = append(num, byte(abs&0xff))
+		abs >>= 8
+	}
+	// the most significant bit is the sign
+	if num[len(num)-1]&0x80 != 0 {
+		if negative {
+			num = append(num, 0x80)
+		} else {
+			num = append(num, 0x00)
+		}
+	} else if negative {
+		num[len(num)-1] |= 0x80
+	}
+
+	return append([]byte{byte(len(num))}, num...)
+}
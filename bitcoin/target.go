@@ -0,0 +1,50 @@
+package bitcoin
+
+import (
+	"math/big"
+)
+
+var (
+	// target for difficulty 1 (0x1d00ffff)
+	diff1Target = CompactToTarget(0x1d00ffff)
+)
+
+// CompactToTarget: converts the nBits compact representation into the full target
+func CompactToTarget(bits uint32) *big.Int {
+	mantissa := int64(bits & 0x007fffff)
+	exponent := uint(bits >> 24)
+
+	target := big.NewInt(mantissa)
+	if exponent <= 3 {
+		target.Rsh(target, 8*(3-exponent))
+	} else {
+		target.Lsh(target, 8*(exponent-3))
+	}
+	if bits&0x00800000 != 0 {
+		target.Neg(target)
+	}
+	return target
+}
+
+// DifficultyToTarget: returns the target that a hash must meet for the given difficulty
+func DifficultyToTarget(difficulty float64) *big.Int {
+	if difficulty <= 0 {
+		return new(big.Int).Set(diff1Target)
+	}
+	target, _ := new(big.Float).Quo(new(big.Float).SetInt(diff1Target), big.NewFloat(difficulty)).Int(nil)
+	return target
+}
+
+// TargetToDifficulty: returns the difficulty represented by the given target
+func TargetToDifficulty(target *big.Int) float64 {
+	if target.Sign() <= 0 {
+		return 0
+	}
+	difficulty, _ := new(big.Float).Quo(new(big.Float).SetInt(diff1Target), new(big.Float).SetInt(target)).Float64()
+	return difficulty
+}
+
+// HashToBig: interprets a hash in internal byte order as a big number
+func HashToBig(hash []byte) *big.Int {
+	return new(big.Int).SetBytes(ReverseBytes(hash))
+}
@@ -1,11 +1,42 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
+	"os"
+	"stratum-server/bitcoin"
+	"strconv"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+const (
+	// AuthModePassword validates the account password against the accounts table
+	AuthModePassword = "password"
+	// AuthModeAddress expects a payout address as account, validating it for the configured network
+	AuthModeAddress = "address"
+	// AuthModeNone authorizes any username, only meant for testing
+	AuthModeNone = "none"
+
+	// MiningModePool pays the block reward to the pool payout script
+	MiningModePool = "pool"
+	// MiningModeSolo pays the block reward to the payout address of the finder
+	MiningModeSolo = "solo"
+
+	// ProtocolDialectStratum encodes the responses as classic Stratum V1, always including id, result and error
+	ProtocolDialectStratum = "stratum"
+	// ProtocolDialectJSONRPC2 encodes the responses as JSON-RPC 2.0 objects
+	ProtocolDialectJSONRPC2 = "jsonrpc2"
+
+	// PayoutSchemePPLNS splits the reward of every found block between the last N shares
+	PayoutSchemePPLNS = "pplns"
+	// PayoutSchemePPS pays the expected block subsidy of every accepted share
+	PayoutSchemePPS = "pps"
+	// PayoutSchemeFPPS pays the expected block subsidy and transaction fees of every accepted share
+	PayoutSchemeFPPS = "fpps"
+)
+
 // PostgreSQLTableConfig represents the specific PostgreSQLtable config
 type PostgreSQLTableConfig struct {
 	Schema string
@@ -20,21 +51,148 @@ type PostgreSQLConfig struct {
 	DB                 string
 	Port               int64
 	SubscriptionsTable PostgreSQLTableConfig
+	BlocksTable        PostgreSQLTableConfig
+	SharesTable        PostgreSQLTableConfig
+	AccountsTable      PostgreSQLTableConfig
+	CreditsTable       PostgreSQLTableConfig
+	PaymentsTable      PostgreSQLTableConfig
+	InstancesTable     PostgreSQLTableConfig
+}
+
+// VardiffConfig represents the variable difficulty config.
+type VardiffConfig struct {
+	InitialDifficulty float64
+	MinDifficulty     float64
+	MaxDifficulty     float64
+	// expected time between shares for every connection
+	TargetShareTime  time.Duration
+	RetargetInterval time.Duration
+	// weight given to the new difficulty estimation, between 0 and 1
+	Smoothing float64
+}
+
+// TLSConfig represents the config for the wss and stratum+ssl listeners.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// enables mutual TLS when present
+	ClientCAFile string
+	// listeners are disabled when empty
+	HTTPSPort string
+	TCPPort   string
+}
+
+// Enabled: returns true if any TLS listener is configured
+func (c TLSConfig) Enabled() bool {
+	return c.HTTPSPort != "" || c.TCPPort != ""
+}
+
+// NodeConfig represents the config of the bitcoind-compatible node used to get block templates.
+type NodeConfig struct {
+	// templates are not fetched when empty
+	RPCURL      string
+	RPCUser     string
+	RPCPassword string
+	// used when the node doesn't support longpoll
+	PollInterval time.Duration
+	// the server isn't ready when the last template is older
+	TemplateMaxAge time.Duration
+}
+
+// PoolConfig represents the config used to build the coinbase transaction.
+type PoolConfig struct {
+	// pool or solo
+	Mode string
+	// hex encoded script receiving the block reward, not used in solo mode
+	PayoutScript string
+	CoinbaseTag  string
+	// block version bits the miners can roll (BIP 310), version rolling is disabled when 0
+	VersionRollingMask uint32
+}
+
+// PayoutConfig represents the config used to split the rewards between the pool accounts.
+type PayoutConfig struct {
+	// pplns, pps or fpps
+	Scheme string
+	// amount of difficulty-weighted shares rewarded on every block
+	PPLNSWindow float64
+	// fraction of the reward kept by the pool
+	PoolFee float64
+}
+
+// PaymentConfig represents the config used to pay the credit balances through the node's wallet.
+type PaymentConfig struct {
+	Interval time.Duration
+	// minimum balance paid, in satoshis
+	Threshold int64
+	// payments are disabled when empty
+	WalletRPCURL      string
+	WalletRPCUser     string
+	WalletRPCPassword string
+}
+
+// InstanceConfig represents the config used to track which server instance holds every session.
+type InstanceConfig struct {
+	// must be stable across restarts, so that the sessions orphaned by a crash are freed on startup
+	ID                string
+	HeartbeatInterval time.Duration
+	// sessions held by instances that didn't heartbeat for longer are freed
+	HeartbeatTimeout time.Duration
+}
+
+// ShutdownConfig represents the config used to drain the connections before exiting.
+type ShutdownConfig struct {
+	// max time waiting for the miners to reconnect somewhere else
+	DrainTimeout time.Duration
+	// sent in client.reconnect, miners reconnect to the same host when empty
+	ReconnectHost string
+	ReconnectPort int64
 }
 
 // Config represents main config.
 type Config struct {
 	HTTPPort string
+	// stratum+tcp listener is disabled when empty
+	TCPPort string
+	// bearer token of the admin API, disabled when empty
+	AdminAPIToken string
+	// how mining.authorize credentials are validated
+	AuthMode string
+	// mainnet, testnet or regtest
+	Network string
+	// stratum or jsonrpc2
+	ProtocolDialect string
+	TLSConfig
 	PostgreSQLConfig
+	VardiffConfig
+	NodeConfig
+	PoolConfig
+	PayoutConfig
+	PaymentConfig
+	InstanceConfig
+	ShutdownConfig
 }
 
 // InitConfig: loads required configuration
 func InitConfig() (*Config, error) {
 	v := viper.New()
 	v.AutomaticEnv()
+	setDefaults(v)
 
 	c := Config{
-		HTTPPort: v.GetString(httpPort),
+		HTTPPort:        v.GetString(httpPort),
+		TCPPort:         v.GetString(tcpPort),
+		AdminAPIToken:   v.GetString(adminAPIToken),
+		AuthMode:        v.GetString(authMode),
+		Network:         v.GetString(bitcoinNetwork),
+		ProtocolDialect: v.GetString(protocolDialect),
+		TLSConfig: TLSConfig{
+			CertFile:     v.GetString(tlsCertFile),
+			KeyFile:      v.GetString(tlsKeyFile),
+			ClientCAFile: v.GetString(tlsClientCAFile),
+			HTTPSPort:    v.GetString(tlsHTTPSPort),
+			TCPPort:      v.GetString(tlsTCPPort),
+		},
 		PostgreSQLConfig: PostgreSQLConfig{
 			Host:     v.GetString(postgreSQLHost),
 			User:     v.GetString(postgreSQLUser),
@@ -45,16 +203,140 @@ func InitConfig() (*Config, error) {
 				Schema: v.GetString(postgreSQLSubscriptionsTableSchema),
 				Name:   v.GetString(postgreSQLSubscriptionsTableName),
 			},
+			BlocksTable: PostgreSQLTableConfig{
+				Schema: v.GetString(postgreSQLBlocksTableSchema),
+				Name:   v.GetString(postgreSQLBlocksTableName),
+			},
+			SharesTable: PostgreSQLTableConfig{
+				Schema: v.GetString(postgreSQLSharesTableSchema),
+				Name:   v.GetString(postgreSQLSharesTableName),
+			},
+			AccountsTable: PostgreSQLTableConfig{
+				Schema: v.GetString(postgreSQLAccountsTableSchema),
+				Name:   v.GetString(postgreSQLAccountsTableName),
+			},
+			CreditsTable: PostgreSQLTableConfig{
+				Schema: v.GetString(postgreSQLCreditsTableSchema),
+				Name:   v.GetString(postgreSQLCreditsTableName),
+			},
+			PaymentsTable: PostgreSQLTableConfig{
+				Schema: v.GetString(postgreSQLPaymentsTableSchema),
+				Name:   v.GetString(postgreSQLPaymentsTableName),
+			},
+			InstancesTable: PostgreSQLTableConfig{
+				Schema: v.GetString(postgreSQLInstancesTableSchema),
+				Name:   v.GetString(postgreSQLInstancesTableName),
+			},
+		},
+		VardiffConfig: VardiffConfig{
+			InitialDifficulty: v.GetFloat64(vardiffInitialDifficulty),
+			MinDifficulty:     v.GetFloat64(vardiffMinDifficulty),
+			MaxDifficulty:     v.GetFloat64(vardiffMaxDifficulty),
+			TargetShareTime:   v.GetDuration(vardiffTargetShareTime),
+			RetargetInterval:  v.GetDuration(vardiffRetargetInterval),
+			Smoothing:         v.GetFloat64(vardiffSmoothing),
+		},
+		NodeConfig: NodeConfig{
+			RPCURL:         v.GetString(nodeRPCURL),
+			RPCUser:        v.GetString(nodeRPCUser),
+			RPCPassword:    v.GetString(nodeRPCPassword),
+			PollInterval:   v.GetDuration(nodePollInterval),
+			TemplateMaxAge: v.GetDuration(nodeTemplateMaxAge),
+		},
+		PoolConfig: PoolConfig{
+			Mode:         v.GetString(poolMode),
+			PayoutScript: v.GetString(poolPayoutScript),
+			CoinbaseTag:  v.GetString(poolCoinbaseTag),
+		},
+		PayoutConfig: PayoutConfig{
+			Scheme:      v.GetString(payoutScheme),
+			PPLNSWindow: v.GetFloat64(payoutPPLNSWindow),
+			PoolFee:     v.GetFloat64(payoutPoolFee),
+		},
+		PaymentConfig: PaymentConfig{
+			Interval:          v.GetDuration(paymentInterval),
+			Threshold:         v.GetInt64(paymentThreshold),
+			WalletRPCURL:      v.GetString(paymentWalletRPCURL),
+			WalletRPCUser:     v.GetString(paymentWalletRPCUser),
+			WalletRPCPassword: v.GetString(paymentWalletRPCPassword),
+		},
+		InstanceConfig: InstanceConfig{
+			ID:                v.GetString(instanceID),
+			HeartbeatInterval: v.GetDuration(instanceHeartbeatInterval),
+			HeartbeatTimeout:  v.GetDuration(instanceHeartbeatTimeout),
+		},
+		ShutdownConfig: ShutdownConfig{
+			DrainTimeout:  v.GetDuration(shutdownDrainTimeout),
+			ReconnectHost: v.GetString(shutdownReconnectHost),
+			ReconnectPort: v.GetInt64(shutdownReconnectPort),
 		},
 	}
 
 	if err := validateConfig(v); err != nil {
 		return nil, err
 	}
+	versionRollingMask, err := strconv.ParseUint(v.GetString(poolVersionRollingMask), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", poolVersionRollingMask, err)
+	}
+	c.PoolConfig.VersionRollingMask = uint32(versionRollingMask)
+	if c.TLSConfig.Enabled() && (c.TLSConfig.CertFile == "" || c.TLSConfig.KeyFile == "") {
+		return nil, fmt.Errorf("missing TLS certificate: both %s and %s are required", tlsCertFile, tlsKeyFile)
+	}
+	if c.PoolConfig.Mode == MiningModeSolo && c.AuthMode != AuthModeAddress {
+		return nil, fmt.Errorf("solo mode requires %s=%s", authMode, AuthModeAddress)
+	}
+	if c.NodeConfig.RPCURL != "" && c.PoolConfig.Mode == MiningModePool && c.PoolConfig.PayoutScript == "" {
+		return nil, fmt.Errorf("missing mandatory environment variable: %s", poolPayoutScript)
+	}
+	if _, err := hex.DecodeString(c.PoolConfig.PayoutScript); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", poolPayoutScript, err)
+	}
 
 	return &c, nil
 }
 
+func setDefaults(viper *viper.Viper) {
+	viper.SetDefault(postgreSQLBlocksTableSchema, "public")
+	viper.SetDefault(postgreSQLBlocksTableName, "blocks")
+	viper.SetDefault(postgreSQLSharesTableSchema, "public")
+	viper.SetDefault(postgreSQLSharesTableName, "shares")
+	viper.SetDefault(postgreSQLAccountsTableSchema, "public")
+	viper.SetDefault(postgreSQLAccountsTableName, "accounts")
+	viper.SetDefault(postgreSQLCreditsTableSchema, "public")
+	viper.SetDefault(postgreSQLCreditsTableName, "credits")
+	viper.SetDefault(postgreSQLPaymentsTableSchema, "public")
+	viper.SetDefault(postgreSQLPaymentsTableName, "payments")
+	viper.SetDefault(postgreSQLInstancesTableSchema, "public")
+	viper.SetDefault(postgreSQLInstancesTableName, "instances")
+	viper.SetDefault(authMode, AuthModePassword)
+	viper.SetDefault(bitcoinNetwork, bitcoin.Mainnet)
+	viper.SetDefault(protocolDialect, ProtocolDialectStratum)
+	viper.SetDefault(nodePollInterval, 5*time.Second)
+	viper.SetDefault(nodeTemplateMaxAge, 10*time.Minute)
+	viper.SetDefault(poolMode, MiningModePool)
+	viper.SetDefault(poolCoinbaseTag, "/stratum-server/")
+	// general purpose bits defined in BIP 320
+	viper.SetDefault(poolVersionRollingMask, "1fffe000")
+	viper.SetDefault(payoutScheme, PayoutSchemePPLNS)
+	viper.SetDefault(payoutPPLNSWindow, 1000000)
+	viper.SetDefault(payoutPoolFee, 0)
+	viper.SetDefault(paymentInterval, time.Hour)
+	viper.SetDefault(paymentThreshold, 1000000)
+	if hostname, err := os.Hostname(); err == nil {
+		viper.SetDefault(instanceID, hostname)
+	}
+	viper.SetDefault(instanceHeartbeatInterval, 10*time.Second)
+	viper.SetDefault(instanceHeartbeatTimeout, time.Minute)
+	viper.SetDefault(shutdownDrainTimeout, 30*time.Second)
+	viper.SetDefault(vardiffInitialDifficulty, 1)
+	viper.SetDefault(vardiffMinDifficulty, 1)
+	viper.SetDefault(vardiffMaxDifficulty, 1<<32)
+	viper.SetDefault(vardiffTargetShareTime, 10*time.Second)
+	viper.SetDefault(vardiffRetargetInterval, 90*time.Second)
+	viper.SetDefault(vardiffSmoothing, 0.5)
+}
+
 func validateConfig(viper *viper.Viper) error {
 	mandatoryVariables := []string{
 		httpPort,
@@ -73,5 +355,71 @@ func validateConfig(viper *viper.Viper) error {
 		}
 	}
 
+	switch viper.GetString(authMode) {
+	case AuthModePassword, AuthModeAddress, AuthModeNone:
+	default:
+		return fmt.Errorf("invalid %s: %s", authMode, viper.GetString(authMode))
+	}
+	switch viper.GetString(protocolDialect) {
+	case ProtocolDialectStratum, ProtocolDialectJSONRPC2:
+	default:
+		return fmt.Errorf("invalid %s: %s", protocolDialect, viper.GetString(protocolDialect))
+	}
+	switch viper.GetString(poolMode) {
+	case MiningModePool, MiningModeSolo:
+	default:
+		return fmt.Errorf("invalid %s: %s", poolMode, viper.GetString(poolMode))
+	}
+	if !bitcoin.IsValidNetwork(viper.GetString(bitcoinNetwork)) {
+		return fmt.Errorf("invalid %s: %s", bitcoinNetwork, viper.GetString(bitcoinNetwork))
+	}
+
+	switch viper.GetString(payoutScheme) {
+	case PayoutSchemePPLNS, PayoutSchemePPS, PayoutSchemeFPPS:
+	default:
+		return fmt.Errorf("invalid %s: %s", payoutScheme, viper.GetString(payoutScheme))
+	}
+	if viper.GetFloat64(payoutPPLNSWindow) <= 0 {
+		return fmt.Errorf("invalid %s: must be greater than 0", payoutPPLNSWindow)
+	}
+	if fee := viper.GetFloat64(payoutPoolFee); fee < 0 || fee >= 1 {
+		return fmt.Errorf("invalid %s: must be between 0 and 1", payoutPoolFee)
+	}
+
+	if viper.GetDuration(paymentInterval) <= 0 || viper.GetInt64(paymentThreshold) <= 0 {
+		return fmt.Errorf("invalid payment interval or threshold")
+	}
+
+	if viper.GetString(instanceID) == "" {
+		return fmt.Errorf("missing mandatory environment variable: %s", instanceID)
+	}
+	heartbeatInterval := viper.GetDuration(instanceHeartbeatInterval)
+	if heartbeatInterval <= 0 || viper.GetDuration(instanceHeartbeatTimeout) <= heartbeatInterval {
+		return fmt.Errorf("invalid %s: must be greater than %s", instanceHeartbeatTimeout, instanceHeartbeatInterval)
+	}
+
+	if viper.GetDuration(shutdownDrainTimeout) <= 0 {
+		return fmt.Errorf("invalid %s: must be greater than 0", shutdownDrainTimeout)
+	}
+	if viper.GetInt64(shutdownReconnectPort) != 0 && viper.GetString(shutdownReconnectHost) == "" {
+		return fmt.Errorf("%s requires %s", shutdownReconnectPort, shutdownReconnectHost)
+	}
+
+	minDifficulty := viper.GetFloat64(vardiffMinDifficulty)
+	if minDifficulty <= 0 || minDifficulty > viper.GetFloat64(vardiffMaxDifficulty) {
+		return fmt.Errorf("invalid vardiff difficulty bounds")
+	}
+	// sent to every miner until the first retarget
+	if initialDifficulty := viper.GetFloat64(vardiffInitialDifficulty); initialDifficulty < minDifficulty ||
+		initialDifficulty > viper.GetFloat64(vardiffMaxDifficulty) {
+		return fmt.Errorf("invalid vardiff initial difficulty: must be between the min and max difficulty")
+	}
+	if viper.GetDuration(vardiffTargetShareTime) <= 0 || viper.GetDuration(vardiffRetargetInterval) <= 0 {
+		return fmt.Errorf("invalid vardiff durations")
+	}
+	if smoothing := viper.GetFloat64(vardiffSmoothing); smoothing <= 0 || smoothing > 1 {
+		return fmt.Errorf("invalid vardiff smoothing: must be between 0 and 1")
+	}
+
 	return nil
 }
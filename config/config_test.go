@@ -3,12 +3,16 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestInitConfig(t *testing.T) {
+	hostname, _ := os.Hostname()
+
 	routeTests := []struct {
 		name                 string
 		environmentVariables map[string]string
@@ -132,7 +136,10 @@ func TestInitConfig(t *testing.T) {
 				postgreSQLSubscriptionsTableName:   "subscriptions",
 			},
 			output: &Config{
-				HTTPPort: "8080",
+				HTTPPort:        "8080",
+				AuthMode:        AuthModePassword,
+				Network:         "mainnet",
+				ProtocolDialect: ProtocolDialectStratum,
 				PostgreSQLConfig: PostgreSQLConfig{
 					Host:     "host",
 					User:     "user",
@@ -143,6 +150,365 @@ func TestInitConfig(t *testing.T) {
 						Schema: "public",
 						Name:   "subscriptions",
 					},
+					BlocksTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "blocks",
+					},
+					SharesTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "shares",
+					},
+					AccountsTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "accounts",
+					},
+					CreditsTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "credits",
+					},
+					PaymentsTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "payments",
+					},
+					InstancesTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "instances",
+					},
+				},
+				VardiffConfig: VardiffConfig{
+					InitialDifficulty: 1,
+					MinDifficulty:     1,
+					MaxDifficulty:     1 << 32,
+					TargetShareTime:   10 * time.Second,
+					RetargetInterval:  90 * time.Second,
+					Smoothing:         0.5,
+				},
+				NodeConfig: NodeConfig{
+					PollInterval:   5 * time.Second,
+					TemplateMaxAge: 10 * time.Minute,
+				},
+				PoolConfig: PoolConfig{
+					Mode:               MiningModePool,
+					CoinbaseTag:        "/stratum-server/",
+					VersionRollingMask: 0x1fffe000,
+				},
+				PayoutConfig: PayoutConfig{
+					Scheme:      PayoutSchemePPLNS,
+					PPLNSWindow: 1000000,
+				},
+				PaymentConfig: PaymentConfig{
+					Interval:  time.Hour,
+					Threshold: 1000000,
+				},
+				InstanceConfig: InstanceConfig{
+					ID:                hostname,
+					HeartbeatInterval: 10 * time.Second,
+					HeartbeatTimeout:  time.Minute,
+				},
+				ShutdownConfig: ShutdownConfig{
+					DrainTimeout: 30 * time.Second,
+				},
+			},
+		},
+		{
+			name: "error with invalid vardiff bounds",
+			environmentVariables: map[string]string{
+				httpPort:                           "8080",
+				postgreSQLHost:                     "host",
+				postgreSQLUser:                     "user",
+				postgreSQLPassword:                 "pass",
+				postgreSQLDB:                       "db",
+				postgreSQLPort:                     "5234",
+				postgreSQLSubscriptionsTableSchema: "public",
+				postgreSQLSubscriptionsTableName:   "subscriptions",
+				vardiffMinDifficulty:               "1024",
+				vardiffMaxDifficulty:               "512",
+			},
+			expectedError: fmt.Errorf("invalid vardiff difficulty bounds"),
+		},
+		{
+			name: "error with initial difficulty below the min",
+			environmentVariables: map[string]string{
+				httpPort:                           "8080",
+				postgreSQLHost:                     "host",
+				postgreSQLUser:                     "user",
+				postgreSQLPassword:                 "pass",
+				postgreSQLDB:                       "db",
+				postgreSQLPort:                     "5234",
+				postgreSQLSubscriptionsTableSchema: "public",
+				postgreSQLSubscriptionsTableName:   "subscriptions",
+				vardiffInitialDifficulty:           "128",
+				vardiffMinDifficulty:               "256",
+				vardiffMaxDifficulty:               "65536",
+			},
+			expectedError: fmt.Errorf("invalid vardiff initial difficulty: must be between the min and max difficulty"),
+		},
+		{
+			name: "error with initial difficulty above the max",
+			environmentVariables: map[string]string{
+				httpPort:                           "8080",
+				postgreSQLHost:                     "host",
+				postgreSQLUser:                     "user",
+				postgreSQLPassword:                 "pass",
+				postgreSQLDB:                       "db",
+				postgreSQLPort:                     "5234",
+				postgreSQLSubscriptionsTableSchema: "public",
+				postgreSQLSubscriptionsTableName:   "subscriptions",
+				vardiffInitialDifficulty:           "131072",
+				vardiffMinDifficulty:               "256",
+				vardiffMaxDifficulty:               "65536",
+			},
+			expectedError: fmt.Errorf("invalid vardiff initial difficulty: must be between the min and max difficulty"),
+		},
+		{
+			name: "error with TLS port without certificate",
+			environmentVariables: map[string]string{
+				httpPort:                           "8080",
+				tlsTCPPort:                         "3334",
+				tlsCertFile:                        "/etc/stratum/cert.pem",
+				postgreSQLHost:                     "host",
+				postgreSQLUser:                     "user",
+				postgreSQLPassword:                 "pass",
+				postgreSQLDB:                       "db",
+				postgreSQLPort:                     "5234",
+				postgreSQLSubscriptionsTableSchema: "public",
+				postgreSQLSubscriptionsTableName:   "subscriptions",
+			},
+			expectedError: fmt.Errorf("missing TLS certificate: both %s and %s are required", tlsCertFile, tlsKeyFile),
+		},
+		{
+			name: "error with node without payout script",
+			environmentVariables: map[string]string{
+				httpPort:                           "8080",
+				nodeRPCURL:                         "http://127.0.0.1:8332",
+				postgreSQLHost:                     "host",
+				postgreSQLUser:                     "user",
+				postgreSQLPassword:                 "pass",
+				postgreSQLDB:                       "db",
+				postgreSQLPort:                     "5234",
+				postgreSQLSubscriptionsTableSchema: "public",
+				postgreSQLSubscriptionsTableName:   "subscriptions",
+			},
+			expectedError: fmt.Errorf("missing mandatory environment variable: %s", poolPayoutScript),
+		},
+		{
+			name: "error with solo mode without address auth",
+			environmentVariables: map[string]string{
+				httpPort:                           "8080",
+				poolMode:                           "solo",
+				postgreSQLHost:                     "host",
+				postgreSQLUser:                     "user",
+				postgreSQLPassword:                 "pass",
+				postgreSQLDB:                       "db",
+				postgreSQLPort:                     "5234",
+				postgreSQLSubscriptionsTableSchema: "public",
+				postgreSQLSubscriptionsTableName:   "subscriptions",
+			},
+			expectedError: fmt.Errorf("solo mode requires %s=%s", authMode, AuthModeAddress),
+		},
+		{
+			name: "error with invalid pool fee",
+			environmentVariables: map[string]string{
+				httpPort:                           "8080",
+				payoutPoolFee:                      "1",
+				postgreSQLHost:                     "host",
+				postgreSQLUser:                     "user",
+				postgreSQLPassword:                 "pass",
+				postgreSQLDB:                       "db",
+				postgreSQLPort:                     "5234",
+				postgreSQLSubscriptionsTableSchema: "public",
+				postgreSQLSubscriptionsTableName:   "subscriptions",
+			},
+			expectedError: fmt.Errorf("invalid %s: must be between 0 and 1", payoutPoolFee),
+		},
+		{
+			name: "error with invalid version rolling mask",
+			environmentVariables: map[string]string{
+				httpPort:                           "8080",
+				poolVersionRollingMask:             "1fffe000ff",
+				postgreSQLHost:                     "host",
+				postgreSQLUser:                     "user",
+				postgreSQLPassword:                 "pass",
+				postgreSQLDB:                       "db",
+				postgreSQLPort:                     "5234",
+				postgreSQLSubscriptionsTableSchema: "public",
+				postgreSQLSubscriptionsTableName:   "subscriptions",
+			},
+			expectedError: fmt.Errorf("invalid %s: %v", poolVersionRollingMask, &strconv.NumError{Func: "ParseUint", Num: "1fffe000ff", Err: strconv.ErrRange}),
+		},
+		{
+			name: "error with reconnect port without host",
+			environmentVariables: map[string]string{
+				httpPort:                           "8080",
+				shutdownReconnectPort:              "3333",
+				postgreSQLHost:                     "host",
+				postgreSQLUser:                     "user",
+				postgreSQLPassword:                 "pass",
+				postgreSQLDB:                       "db",
+				postgreSQLPort:                     "5234",
+				postgreSQLSubscriptionsTableSchema: "public",
+				postgreSQLSubscriptionsTableName:   "subscriptions",
+			},
+			expectedError: fmt.Errorf("%s requires %s", shutdownReconnectPort, shutdownReconnectHost),
+		},
+		{
+			name: "error with heartbeat timeout shorter than the interval",
+			environmentVariables: map[string]string{
+				httpPort:                           "8080",
+				instanceHeartbeatInterval:          "1m",
+				instanceHeartbeatTimeout:           "30s",
+				postgreSQLHost:                     "host",
+				postgreSQLUser:                     "user",
+				postgreSQLPassword:                 "pass",
+				postgreSQLDB:                       "db",
+				postgreSQLPort:                     "5234",
+				postgreSQLSubscriptionsTableSchema: "public",
+				postgreSQLSubscriptionsTableName:   "subscriptions",
+			},
+			expectedError: fmt.Errorf("invalid %s: must be greater than %s", instanceHeartbeatTimeout, instanceHeartbeatInterval),
+		},
+		{
+			name: "no error with optional config",
+			environmentVariables: map[string]string{
+				httpPort:                           "8080",
+				tcpPort:                            "3333",
+				adminAPIToken:                      "secret",
+				authMode:                           "address",
+				bitcoinNetwork:                     "testnet",
+				protocolDialect:                    "jsonrpc2",
+				tlsCertFile:                        "/etc/stratum/cert.pem",
+				tlsKeyFile:                         "/etc/stratum/key.pem",
+				tlsClientCAFile:                    "/etc/stratum/ca.pem",
+				tlsHTTPSPort:                       "8443",
+				tlsTCPPort:                         "3334",
+				postgreSQLHost:                     "host",
+				postgreSQLUser:                     "user",
+				postgreSQLPassword:                 "pass",
+				postgreSQLDB:                       "db",
+				postgreSQLPort:                     "5234",
+				postgreSQLSubscriptionsTableSchema: "public",
+				postgreSQLSubscriptionsTableName:   "subscriptions",
+				vardiffInitialDifficulty:           "512",
+				vardiffMinDifficulty:               "256",
+				vardiffMaxDifficulty:               "65536",
+				vardiffTargetShareTime:             "15s",
+				vardiffRetargetInterval:            "2m",
+				vardiffSmoothing:                   "0.25",
+				nodeRPCURL:                         "http://127.0.0.1:8332",
+				nodeRPCUser:                        "rpcuser",
+				nodeRPCPassword:                    "rpcpass",
+				nodePollInterval:                   "1s",
+				nodeTemplateMaxAge:                 "5m",
+				poolMode:                           "solo",
+				poolPayoutScript:                   "0014751e76e8199196d454941c45d1b3a323f1433bd6",
+				poolCoinbaseTag:                    "/pool/",
+				poolVersionRollingMask:             "0",
+				payoutScheme:                       "fpps",
+				payoutPPLNSWindow:                  "500000",
+				payoutPoolFee:                      "0.02",
+				paymentInterval:                    "10m",
+				paymentThreshold:                   "50000",
+				paymentWalletRPCURL:                "http://127.0.0.1:8332/wallet/pool",
+				paymentWalletRPCUser:               "walletuser",
+				paymentWalletRPCPassword:           "walletpass",
+				instanceID:                         "stratum-0",
+				instanceHeartbeatInterval:          "5s",
+				instanceHeartbeatTimeout:           "30s",
+				shutdownDrainTimeout:               "1m",
+				shutdownReconnectHost:              "backup.pool.example",
+				shutdownReconnectPort:              "3333",
+			},
+			output: &Config{
+				HTTPPort:        "8080",
+				TCPPort:         "3333",
+				AdminAPIToken:   "secret",
+				AuthMode:        AuthModeAddress,
+				Network:         "testnet",
+				ProtocolDialect: ProtocolDialectJSONRPC2,
+				TLSConfig: TLSConfig{
+					CertFile:     "/etc/stratum/cert.pem",
+					KeyFile:      "/etc/stratum/key.pem",
+					ClientCAFile: "/etc/stratum/ca.pem",
+					HTTPSPort:    "8443",
+					TCPPort:      "3334",
+				},
+				PostgreSQLConfig: PostgreSQLConfig{
+					Host:     "host",
+					User:     "user",
+					Password: "pass",
+					DB:       "db",
+					Port:     5234,
+					SubscriptionsTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "subscriptions",
+					},
+					BlocksTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "blocks",
+					},
+					SharesTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "shares",
+					},
+					AccountsTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "accounts",
+					},
+					CreditsTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "credits",
+					},
+					PaymentsTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "payments",
+					},
+					InstancesTable: PostgreSQLTableConfig{
+						Schema: "public",
+						Name:   "instances",
+					},
+				},
+				VardiffConfig: VardiffConfig{
+					InitialDifficulty: 512,
+					MinDifficulty:     256,
+					MaxDifficulty:     65536,
+					TargetShareTime:   15 * time.Second,
+					RetargetInterval:  2 * time.Minute,
+					Smoothing:         0.25,
+				},
+				NodeConfig: NodeConfig{
+					RPCURL:         "http://127.0.0.1:8332",
+					RPCUser:        "rpcuser",
+					RPCPassword:    "rpcpass",
+					PollInterval:   time.Second,
+					TemplateMaxAge: 5 * time.Minute,
+				},
+				PoolConfig: PoolConfig{
+					Mode:               MiningModeSolo,
+					PayoutScript:       "0014751e76e8199196d454941c45d1b3a323f1433bd6",
+					CoinbaseTag:        "/pool/",
+					VersionRollingMask: 0,
+				},
+				PayoutConfig: PayoutConfig{
+					Scheme:      PayoutSchemeFPPS,
+					PPLNSWindow: 500000,
+					PoolFee:     0.02,
+				},
+				PaymentConfig: PaymentConfig{
+					Interval:          10 * time.Minute,
+					Threshold:         50000,
+					WalletRPCURL:      "http://127.0.0.1:8332/wallet/pool",
+					WalletRPCUser:     "walletuser",
+					WalletRPCPassword: "walletpass",
+				},
+				InstanceConfig: InstanceConfig{
+					ID:                "stratum-0",
+					HeartbeatInterval: 5 * time.Second,
+					HeartbeatTimeout:  30 * time.Second,
+				},
+				ShutdownConfig: ShutdownConfig{
+					DrainTimeout:  time.Minute,
+					ReconnectHost: "backup.pool.example",
+					ReconnectPort: 3333,
 				},
 			},
 		},
@@ -151,6 +517,13 @@ func TestInitConfig(t *testing.T) {
 	for _, tt := range routeTests {
 		t.Run(tt.name, func(t *testing.T) {
 			_ = os.Unsetenv(httpPort)
+			_ = os.Unsetenv(tcpPort)
+			_ = os.Unsetenv(adminAPIToken)
+			_ = os.Unsetenv(tlsCertFile)
+			_ = os.Unsetenv(tlsKeyFile)
+			_ = os.Unsetenv(tlsClientCAFile)
+			_ = os.Unsetenv(tlsHTTPSPort)
+			_ = os.Unsetenv(tlsTCPPort)
 			_ = os.Unsetenv(postgreSQLHost)
 			_ = os.Unsetenv(postgreSQLUser)
 			_ = os.Unsetenv(postgreSQLPassword)
@@ -158,6 +531,50 @@ func TestInitConfig(t *testing.T) {
 			_ = os.Unsetenv(postgreSQLPort)
 			_ = os.Unsetenv(postgreSQLSubscriptionsTableSchema)
 			_ = os.Unsetenv(postgreSQLSubscriptionsTableName)
+			_ = os.Unsetenv(postgreSQLBlocksTableSchema)
+			_ = os.Unsetenv(postgreSQLBlocksTableName)
+			_ = os.Unsetenv(postgreSQLSharesTableSchema)
+			_ = os.Unsetenv(postgreSQLSharesTableName)
+			_ = os.Unsetenv(postgreSQLAccountsTableSchema)
+			_ = os.Unsetenv(postgreSQLAccountsTableName)
+			_ = os.Unsetenv(postgreSQLCreditsTableSchema)
+			_ = os.Unsetenv(postgreSQLCreditsTableName)
+			_ = os.Unsetenv(postgreSQLPaymentsTableSchema)
+			_ = os.Unsetenv(postgreSQLPaymentsTableName)
+			_ = os.Unsetenv(authMode)
+			_ = os.Unsetenv(bitcoinNetwork)
+			_ = os.Unsetenv(protocolDialect)
+			_ = os.Unsetenv(nodeRPCURL)
+			_ = os.Unsetenv(nodeRPCUser)
+			_ = os.Unsetenv(nodeRPCPassword)
+			_ = os.Unsetenv(nodePollInterval)
+			_ = os.Unsetenv(nodeTemplateMaxAge)
+			_ = os.Unsetenv(poolMode)
+			_ = os.Unsetenv(poolPayoutScript)
+			_ = os.Unsetenv(poolCoinbaseTag)
+			_ = os.Unsetenv(poolVersionRollingMask)
+			_ = os.Unsetenv(payoutScheme)
+			_ = os.Unsetenv(payoutPPLNSWindow)
+			_ = os.Unsetenv(payoutPoolFee)
+			_ = os.Unsetenv(paymentInterval)
+			_ = os.Unsetenv(paymentThreshold)
+			_ = os.Unsetenv(paymentWalletRPCURL)
+			_ = os.Unsetenv(paymentWalletRPCUser)
+			_ = os.Unsetenv(paymentWalletRPCPassword)
+			_ = os.Unsetenv(postgreSQLInstancesTableSchema)
+			_ = os.Unsetenv(postgreSQLInstancesTableName)
+			_ = os.Unsetenv(instanceID)
+			_ = os.Unsetenv(instanceHeartbeatInterval)
+			_ = os.Unsetenv(instanceHeartbeatTimeout)
+			_ = os.Unsetenv(shutdownDrainTimeout)
+			_ = os.Unsetenv(shutdownReconnectHost)
+			_ = os.Unsetenv(shutdownReconnectPort)
+			_ = os.Unsetenv(vardiffInitialDifficulty)
+			_ = os.Unsetenv(vardiffMinDifficulty)
+			_ = os.Unsetenv(vardiffMaxDifficulty)
+			_ = os.Unsetenv(vardiffTargetShareTime)
+			_ = os.Unsetenv(vardiffRetargetInterval)
+			_ = os.Unsetenv(vardiffSmoothing)
 
 			for k, v := range tt.environmentVariables {
 				_ = os.Setenv(k, v)
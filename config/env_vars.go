@@ -2,6 +2,15 @@ package config
 
 const (
 	httpPort = "HTTP_PORT"
+	tcpPort  = "TCP_PORT"
+
+	adminAPIToken = "ADMIN_API_TOKEN"
+
+	tlsCertFile     = "TLS_CERT_FILE"
+	tlsKeyFile      = "TLS_KEY_FILE"
+	tlsClientCAFile = "TLS_CLIENT_CA_FILE"
+	tlsHTTPSPort    = "TLS_HTTPS_PORT"
+	tlsTCPPort      = "TLS_TCP_PORT"
 
 	postgreSQLHost                     = "POSTGRES_HOST"
 	postgreSQLUser                     = "POSTGRES_USER"
@@ -10,4 +19,56 @@ const (
 	postgreSQLPort                     = "POSTGRES_PORT"
 	postgreSQLSubscriptionsTableSchema = "POSTGRES_SUBSCRIPTIONS_TABLE_SCHEMA"
 	postgreSQLSubscriptionsTableName   = "POSTGRES_SUBSCRIPTIONS_TABLE_NAME"
+	postgreSQLBlocksTableSchema        = "POSTGRES_BLOCKS_TABLE_SCHEMA"
+	postgreSQLBlocksTableName          = "POSTGRES_BLOCKS_TABLE_NAME"
+	postgreSQLSharesTableSchema        = "POSTGRES_SHARES_TABLE_SCHEMA"
+	postgreSQLSharesTableName          = "POSTGRES_SHARES_TABLE_NAME"
+	postgreSQLAccountsTableSchema      = "POSTGRES_ACCOUNTS_TABLE_SCHEMA"
+	postgreSQLAccountsTableName        = "POSTGRES_ACCOUNTS_TABLE_NAME"
+	postgreSQLCreditsTableSchema       = "POSTGRES_CREDITS_TABLE_SCHEMA"
+	postgreSQLCreditsTableName         = "POSTGRES_CREDITS_TABLE_NAME"
+	postgreSQLPaymentsTableSchema      = "POSTGRES_PAYMENTS_TABLE_SCHEMA"
+	postgreSQLPaymentsTableName        = "POSTGRES_PAYMENTS_TABLE_NAME"
+	postgreSQLInstancesTableSchema     = "POSTGRES_INSTANCES_TABLE_SCHEMA"
+	postgreSQLInstancesTableName       = "POSTGRES_INSTANCES_TABLE_NAME"
+
+	authMode        = "AUTH_MODE"
+	bitcoinNetwork  = "BITCOIN_NETWORK"
+	protocolDialect = "PROTOCOL_DIALECT"
+
+	nodeRPCURL         = "NODE_RPC_URL"
+	nodeRPCUser        = "NODE_RPC_USER"
+	nodeRPCPassword    = "NODE_RPC_PASSWORD"
+	nodePollInterval   = "NODE_POLL_INTERVAL"
+	nodeTemplateMaxAge = "NODE_TEMPLATE_MAX_AGE"
+
+	poolMode               = "POOL_MODE"
+	poolPayoutScript       = "POOL_PAYOUT_SCRIPT"
+	poolCoinbaseTag        = "POOL_COINBASE_TAG"
+	poolVersionRollingMask = "POOL_VERSION_ROLLING_MASK"
+
+	payoutScheme      = "PAYOUT_SCHEME"
+	payoutPPLNSWindow = "PAYOUT_PPLNS_WINDOW"
+	payoutPoolFee     = "PAYOUT_POOL_FEE"
+
+	paymentInterval          = "PAYMENT_INTERVAL"
+	paymentThreshold         = "PAYMENT_THRESHOLD"
+	paymentWalletRPCURL      = "WALLET_RPC_URL"
+	paymentWalletRPCUser     = "WALLET_RPC_USER"
+	paymentWalletRPCPassword = "WALLET_RPC_PASSWORD"
+
+	instanceID                = "INSTANCE_ID"
+	instanceHeartbeatInterval = "INSTANCE_HEARTBEAT_INTERVAL"
+	instanceHeartbeatTimeout  = "INSTANCE_HEARTBEAT_TIMEOUT"
+
+	shutdownDrainTimeout  = "SHUTDOWN_DRAIN_TIMEOUT"
+	shutdownReconnectHost = "SHUTDOWN_RECONNECT_HOST"
+	shutdownReconnectPort = "SHUTDOWN_RECONNECT_PORT"
+
+	vardiffInitialDifficulty = "VARDIFF_INITIAL_DIFFICULTY"
+	vardiffMinDifficulty     = "VARDIFF_MIN_DIFFICULTY"
+	vardiffMaxDifficulty     = "VARDIFF_MAX_DIFFICULTY"
+	vardiffTargetShareTime   = "VARDIFF_TARGET_SHARE_TIME"
+	vardiffRetargetInterval  = "VARDIFF_RETARGET_INTERVAL"
+	vardiffSmoothing         = "VARDIFF_SMOOTHING"
 )
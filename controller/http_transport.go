@@ -10,27 +10,50 @@ import (
 )
 
 const (
-	apiResource    = "api"
-	v1Resource     = "v1"
-	healthResource = "health"
-	wsResource     = "ws"
+	apiResource      = "api"
+	v1Resource       = "v1"
+	healthResource   = "health"
+	liveResource     = "live"
+	readyResource    = "ready"
+	wsResource       = "ws"
+	sessionsResource = "sessions"
 )
 
 var (
-	healthEndpoint = fmt.Sprintf("/%s/%s/%s", apiResource, v1Resource, healthResource)
-	wsEndpoint     = fmt.Sprintf("/%s/%s/%s", apiResource, v1Resource, wsResource)
+	healthEndpoint   = fmt.Sprintf("/%s/%s/%s", apiResource, v1Resource, healthResource)
+	liveEndpoint     = fmt.Sprintf("%s/%s", healthEndpoint, liveResource)
+	readyEndpoint    = fmt.Sprintf("%s/%s", healthEndpoint, readyResource)
+	wsEndpoint       = fmt.Sprintf("/%s/%s/%s", apiResource, v1Resource, wsResource)
+	metricsEndpoint  = "/metrics"
+	sessionsEndpoint = fmt.Sprintf("/%s/%s/%s", apiResource, v1Resource, sessionsResource)
+	sessionEndpoint  = fmt.Sprintf("%s/{%s}", sessionsEndpoint, extraNonce1Param)
 )
 
-// NewHandler: create handlers
-func NewHandler(svc service.Service) http.Handler {
+// NewHandler: create handlers. The admin endpoints are disabled when there's no admin token, and the
+// metrics endpoint when there's no metrics handler.
+func NewHandler(svc service.Service, adminToken string, metricsHandler http.Handler) http.Handler {
 	r := chi.NewRouter()
 
 	r.Group(func(r chi.Router) {
 		r.Use(middleware.Recoverer, middleware.StripSlashes, middleware.Logger)
 
 		r.Get(healthEndpoint, health(svc))
+		r.Get(liveEndpoint, health(svc))
+		r.Get(readyEndpoint, ready(svc))
 		r.Get(wsEndpoint, ws(svc))
-
+		if metricsHandler != nil {
+			r.Method(http.MethodGet, metricsEndpoint, metricsHandler)
+		}
+
+		if adminToken != "" {
+			r.Group(func(r chi.Router) {
+				r.Use(adminAuth(adminToken))
+
+				r.Get(sessionsEndpoint, getSessions(svc))
+				r.Get(sessionEndpoint, getSession(svc))
+				r.Delete(sessionEndpoint, deleteSession(svc))
+			})
+		}
 	})
 
 	return r
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"stratum-server/service"
+	"strings"
+)
+
+const (
+	bearerPrefix = "Bearer "
+)
+
+var (
+	errUnauthorized = fmt.Errorf("unauthorized")
+)
+
+// adminAuth: only lets through the requests carrying the admin token as a bearer token
+func adminAuth(token string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if !strings.HasPrefix(header, bearerPrefix) ||
+				subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, bearerPrefix)), []byte(token)) != 1 {
+				encodeHTTPError(&service.AppError{
+					Error:   errUnauthorized,
+					Message: "missing or invalid admin token",
+					Code:    http.StatusUnauthorized,
+				}, w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -13,8 +13,12 @@ type APIError struct {
 }
 
 func encodeHTTPResponse(w http.ResponseWriter, response interface{}) *service.AppError {
+	return encodeHTTPResponseWithStatus(w, http.StatusOK, response)
+}
+
+func encodeHTTPResponseWithStatus(w http.ResponseWriter, status int, response interface{}) *service.AppError {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		return &service.AppError{
@@ -14,3 +14,14 @@ func health(svc service.Service) func(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// ready: returns 503 when any dependency is down, so that load balancers stop routing miners here
+func ready(svc service.Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := svc.Ready(r.Context())
+
+		if err := encodeHTTPResponseWithStatus(w, int(response.Status), response); err != nil {
+			encodeHTTPError(err, w)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"stratum-server/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_health(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		readyStatus    int64
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "live",
+			path:           liveEndpoint,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":200}`,
+		},
+		{
+			name:           "ready",
+			path:           readyEndpoint,
+			readyStatus:    http.StatusOK,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":200,"dependencies":{"postgres":{"status":"ok","latency_ms":1.5}}}`,
+		},
+		{
+			name:           "not ready",
+			path:           readyEndpoint,
+			readyStatus:    http.StatusServiceUnavailable,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":503,"dependencies":{"postgres":{"status":"ok","latency_ms":1.5}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &ServiceMock{
+				HealthFunc: func() *service.HealthResponse {
+					return &service.HealthResponse{Status: http.StatusOK}
+				},
+				ReadyFunc: func(ctx context.Context) *service.ReadinessResponse {
+					return &service.ReadinessResponse{
+						Status: tt.readyStatus,
+						Dependencies: map[string]*service.DependencyStatus{
+							"postgres": {Status: "ok", LatencyMS: 1.5},
+						},
+					}
+				},
+			}
+			h := NewHandler(svc, "", nil)
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"stratum-server/service"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+const (
+	extraNonce1Param = "extraNonce1"
+)
+
+var (
+	errInvalidExtraNonce1 = fmt.Errorf("invalid extraNonce1")
+)
+
+func getSessions(svc service.Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := svc.GetSessions()
+
+		if err := encodeHTTPResponse(w, response); err != nil {
+			encodeHTTPError(err, w)
+		}
+	}
+}
+
+func getSession(svc service.Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		extraNonce1, err := decodeExtraNonce1(r)
+		if err != nil {
+			encodeHTTPError(err, w)
+			return
+		}
+
+		response, err := svc.GetSession(extraNonce1)
+		if err != nil {
+			encodeHTTPError(err, w)
+			return
+		}
+
+		if err := encodeHTTPResponse(w, response); err != nil {
+			encodeHTTPError(err, w)
+		}
+	}
+}
+
+func deleteSession(svc service.Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		extraNonce1, err := decodeExtraNonce1(r)
+		if err != nil {
+			encodeHTTPError(err, w)
+			return
+		}
+
+		if err := svc.CloseSession(extraNonce1); err != nil {
+			encodeHTTPError(err, w)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// decodeExtraNonce1: reads the hex encoded extraNonce1, as sent in the mining.subscribe response
+func decodeExtraNonce1(r *http.Request) (int64, *service.AppError) {
+	// extraNonce1 values are 4 bytes long
+	extraNonce1, err := strconv.ParseUint(chi.URLParam(r, extraNonce1Param), 16, 32)
+	if err != nil {
+		return 0, &service.AppError{
+			Error:   errInvalidExtraNonce1,
+			Message: "extraNonce1 must be hex encoded in 4 bytes",
+			Code:    http.StatusBadRequest,
+		}
+	}
+	return int64(extraNonce1), nil
+}
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"stratum-server/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testAdminToken = "secret"
+)
+
+func TestHandler_sessions(t *testing.T) {
+	session := &service.Session{
+		RemoteAddr:     "127.0.0.1:51234",
+		Transport:      "tcp",
+		Subscriber:     "cgminer/4.10.0",
+		ExtraNonce1:    "0000000f",
+		Workers:        []string{"user.rig1"},
+		Difficulty:     512,
+		ConnectedAt:    time.Date(2021, 5, 3, 12, 0, 0, 0, time.UTC),
+		SharesAccepted: 10,
+		SharesRejected: 1,
+	}
+	notFound := &service.AppError{
+		Error:   fmt.Errorf("session not found"),
+		Message: "no session found for extraNonce1 00000010",
+		Code:    http.StatusNotFound,
+	}
+	svc := &ServiceMock{
+		GetSessionsFunc: func() []*service.Session {
+			return []*service.Session{session}
+		},
+		GetSessionFunc: func(extraNonce1 int64) (*service.Session, *service.AppError) {
+			if extraNonce1 == 0xf {
+				return session, nil
+			}
+			return nil, notFound
+		},
+		CloseSessionFunc: func(extraNonce1 int64) *service.AppError {
+			if extraNonce1 == 0xf {
+				return nil
+			}
+			return notFound
+		},
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+		expectedBody   interface{}
+	}{
+		{
+			name:           "list sessions",
+			method:         http.MethodGet,
+			path:           sessionsEndpoint,
+			token:          testAdminToken,
+			expectedStatus: http.StatusOK,
+			expectedBody:   []*service.Session{session},
+		},
+		{
+			name:           "get session",
+			method:         http.MethodGet,
+			path:           sessionsEndpoint + "/0000000f",
+			token:          testAdminToken,
+			expectedStatus: http.StatusOK,
+			expectedBody:   session,
+		},
+		{
+			name:           "get unknown session",
+			method:         http.MethodGet,
+			path:           sessionsEndpoint + "/00000010",
+			token:          testAdminToken,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   &APIError{Description: "session not found", Message: "no session found for extraNonce1 00000010"},
+		},
+		{
+			name:           "get session with invalid extraNonce1",
+			method:         http.MethodGet,
+			path:           sessionsEndpoint + "/rig1",
+			token:          testAdminToken,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   &APIError{Description: "invalid extraNonce1", Message: "extraNonce1 must be hex encoded in 4 bytes"},
+		},
+		{
+			name:           "get session with extraNonce1 wider than 4 bytes",
+			method:         http.MethodGet,
+			path:           sessionsEndpoint + "/10000000f",
+			token:          testAdminToken,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   &APIError{Description: "invalid extraNonce1", Message: "extraNonce1 must be hex encoded in 4 bytes"},
+		},
+		{
+			name:           "delete session",
+			method:         http.MethodDelete,
+			path:           sessionsEndpoint + "/0000000f",
+			token:          testAdminToken,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "delete unknown session",
+			method:         http.MethodDelete,
+			path:           sessionsEndpoint + "/00000010",
+			token:          testAdminToken,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   &APIError{Description: "session not found", Message: "no session found for extraNonce1 00000010"},
+		},
+		{
+			name:           "missing token",
+			method:         http.MethodGet,
+			path:           sessionsEndpoint,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   &APIError{Description: "unauthorized", Message: "missing or invalid admin token"},
+		},
+		{
+			name:           "invalid token",
+			method:         http.MethodDelete,
+			path:           sessionsEndpoint + "/0000000f",
+			token:          "wrong",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   &APIError{Description: "unauthorized", Message: "missing or invalid admin token"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(svc, testAdminToken, nil)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedBody == nil {
+				assert.Empty(t, rr.Body.String())
+				return
+			}
+			expected, err := json.Marshal(tt.expectedBody)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expected), rr.Body.String())
+		})
+	}
+}
+
+func TestHandler_sessionsDisabled(t *testing.T) {
+	h := NewHandler(&ServiceMock{}, "", nil)
+
+	req := httptest.NewRequest(http.MethodGet, sessionsEndpoint, nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -25,7 +25,7 @@ func TestHandler_ws(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			h := NewHandler(tt.svc)
+			h := NewHandler(tt.svc, "", nil)
 			s := httptest.NewServer(h)
 			defer s.Close()
 
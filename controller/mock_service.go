@@ -6,14 +6,22 @@ package controller
 import (
 	"context"
 	"github.com/gorilla/websocket"
+	"net"
 	"stratum-server/service"
 	"sync"
 )
 
 var (
+	lockServiceMockCloseSession           sync.RWMutex
+	lockServiceMockDrain                  sync.RWMutex
 	lockServiceMockGetExtraNonce2         sync.RWMutex
+	lockServiceMockGetSession             sync.RWMutex
+	lockServiceMockGetSessions            sync.RWMutex
 	lockServiceMockHealth                 sync.RWMutex
+	lockServiceMockReady                  sync.RWMutex
+	lockServiceMockRunTCPConnection       sync.RWMutex
 	lockServiceMockRunWebsocketConnection sync.RWMutex
+	lockServiceMockSetExtraNonce          sync.RWMutex
 )
 
 // Ensure, that ServiceMock does implement service.Service.
@@ -26,15 +34,36 @@ var _ service.Service = &ServiceMock{}
 //
 //         // make and configure a mocked service.Service
 //         mockedService := &ServiceMock{
+//             CloseSessionFunc: func(extraNonce1 int64) *service.AppError {
+// 	               panic("mock out the CloseSession method")
+//             },
+//             DrainFunc: func(ctx context.Context) error {
+// 	               panic("mock out the Drain method")
+//             },
 //             GetExtraNonce2Func: func() int64 {
 // 	               panic("mock out the GetExtraNonce2 method")
 //             },
+//             GetSessionFunc: func(extraNonce1 int64) (*service.Session, *service.AppError) {
+// 	               panic("mock out the GetSession method")
+//             },
+//             GetSessionsFunc: func() []*service.Session {
+// 	               panic("mock out the GetSessions method")
+//             },
 //             HealthFunc: func() *service.HealthResponse {
 // 	               panic("mock out the Health method")
 //             },
+//             ReadyFunc: func(ctx context.Context) *service.ReadinessResponse {
+// 	               panic("mock out the Ready method")
+//             },
+//             RunTCPConnectionFunc: func(ctx context.Context, conn net.Conn)  {
+// 	               panic("mock out the RunTCPConnection method")
+//             },
 //             RunWebsocketConnectionFunc: func(ctx context.Context, conn *websocket.Conn)  {
 // 	               panic("mock out the RunWebsocketConnection method")
 //             },
+//             SetExtraNonceFunc: func(extraNonce1 int64, newExtraNonce1 int64, extraNonce2 int64) (*service.Session, *service.AppError) {
+// 	               panic("mock out the SetExtraNonce method")
+//             },
 //         }
 //
 //         // use mockedService in code that requires service.Service
@@ -42,23 +71,74 @@ var _ service.Service = &ServiceMock{}
 //
 //     }
 type ServiceMock struct {
+	// CloseSessionFunc mocks the CloseSession method.
+	CloseSessionFunc func(extraNonce1 int64) *service.AppError
+
+	// DrainFunc mocks the Drain method.
+	DrainFunc func(ctx context.Context) error
+
 	// GetExtraNonce2Func mocks the GetExtraNonce2 method.
 	GetExtraNonce2Func func() int64
 
+	// GetSessionFunc mocks the GetSession method.
+	GetSessionFunc func(extraNonce1 int64) (*service.Session, *service.AppError)
+
+	// GetSessionsFunc mocks the GetSessions method.
+	GetSessionsFunc func() []*service.Session
+
 	// HealthFunc mocks the Health method.
 	HealthFunc func() *service.HealthResponse
 
+	// ReadyFunc mocks the Ready method.
+	ReadyFunc func(ctx context.Context) *service.ReadinessResponse
+
+	// RunTCPConnectionFunc mocks the RunTCPConnection method.
+	RunTCPConnectionFunc func(ctx context.Context, conn net.Conn)
+
 	// RunWebsocketConnectionFunc mocks the RunWebsocketConnection method.
 	RunWebsocketConnectionFunc func(ctx context.Context, conn *websocket.Conn)
 
+	// SetExtraNonceFunc mocks the SetExtraNonce method.
+	SetExtraNonceFunc func(extraNonce1 int64, newExtraNonce1 int64, extraNonce2 int64) (*service.Session, *service.AppError)
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// CloseSession holds details about calls to the CloseSession method.
+		CloseSession []struct {
+			// ExtraNonce1 is the extraNonce1 argument value.
+			ExtraNonce1 int64
+		}
+		// Drain holds details about calls to the Drain method.
+		Drain []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
 		// GetExtraNonce2 holds details about calls to the GetExtraNonce2 method.
 		GetExtraNonce2 []struct {
 		}
+		// GetSession holds details about calls to the GetSession method.
+		GetSession []struct {
+			// ExtraNonce1 is the extraNonce1 argument value.
+			ExtraNonce1 int64
+		}
+		// GetSessions holds details about calls to the GetSessions method.
+		GetSessions []struct {
+		}
 		// Health holds details about calls to the Health method.
 		Health []struct {
 		}
+		// Ready holds details about calls to the Ready method.
+		Ready []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// RunTCPConnection holds details about calls to the RunTCPConnection method.
+		RunTCPConnection []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Conn is the conn argument value.
+			Conn net.Conn
+		}
 		// RunWebsocketConnection holds details about calls to the RunWebsocketConnection method.
 		RunWebsocketConnection []struct {
 			// Ctx is the ctx argument value.
@@ -66,7 +146,78 @@ type ServiceMock struct {
 			// Conn is the conn argument value.
 			Conn *websocket.Conn
 		}
+		// SetExtraNonce holds details about calls to the SetExtraNonce method.
+		SetExtraNonce []struct {
+			// ExtraNonce1 is the extraNonce1 argument value.
+			ExtraNonce1 int64
+			// NewExtraNonce1 is the newExtraNonce1 argument value.
+			NewExtraNonce1 int64
+			// ExtraNonce2 is the extraNonce2 argument value.
+			ExtraNonce2 int64
+		}
+	}
+}
+
+// CloseSession calls CloseSessionFunc.
+func (mock *ServiceMock) CloseSession(extraNonce1 int64) *service.AppError {
+	if mock.CloseSessionFunc == nil {
+		panic("ServiceMock.CloseSessionFunc: method is nil but Service.CloseSession was just called")
 	}
+	callInfo := struct {
+		ExtraNonce1 int64
+	}{
+		ExtraNonce1: extraNonce1,
+	}
+	lockServiceMockCloseSession.Lock()
+	mock.calls.CloseSession = append(mock.calls.CloseSession, callInfo)
+	lockServiceMockCloseSession.Unlock()
+	return mock.CloseSessionFunc(extraNonce1)
+}
+
+// CloseSessionCalls gets all the calls that were made to CloseSession.
+// Check the length with:
+//     len(mockedService.CloseSessionCalls())
+func (mock *ServiceMock) CloseSessionCalls() []struct {
+	ExtraNonce1 int64
+} {
+	var calls []struct {
+		ExtraNonce1 int64
+	}
+	lockServiceMockCloseSession.RLock()
+	calls = mock.calls.CloseSession
+	lockServiceMockCloseSession.RUnlock()
+	return calls
+}
+
+// Drain calls DrainFunc.
+func (mock *ServiceMock) Drain(ctx context.Context) error {
+	if mock.DrainFunc == nil {
+		panic("ServiceMock.DrainFunc: method is nil but Service.Drain was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	lockServiceMockDrain.Lock()
+	mock.calls.Drain = append(mock.calls.Drain, callInfo)
+	lockServiceMockDrain.Unlock()
+	return mock.DrainFunc(ctx)
+}
+
+// DrainCalls gets all the calls that were made to Drain.
+// Check the length with:
+//     len(mockedService.DrainCalls())
+func (mock *ServiceMock) DrainCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	lockServiceMockDrain.RLock()
+	calls = mock.calls.Drain
+	lockServiceMockDrain.RUnlock()
+	return calls
 }
 
 // GetExtraNonce2 calls GetExtraNonce2Func.
@@ -95,6 +246,63 @@ func (mock *ServiceMock) GetExtraNonce2Calls() []struct {
 	return calls
 }
 
+// GetSession calls GetSessionFunc.
+func (mock *ServiceMock) GetSession(extraNonce1 int64) (*service.Session, *service.AppError) {
+	if mock.GetSessionFunc == nil {
+		panic("ServiceMock.GetSessionFunc: method is nil but Service.GetSession was just called")
+	}
+	callInfo := struct {
+		ExtraNonce1 int64
+	}{
+		ExtraNonce1: extraNonce1,
+	}
+	lockServiceMockGetSession.Lock()
+	mock.calls.GetSession = append(mock.calls.GetSession, callInfo)
+	lockServiceMockGetSession.Unlock()
+	return mock.GetSessionFunc(extraNonce1)
+}
+
+// GetSessionCalls gets all the calls that were made to GetSession.
+// Check the length with:
+//     len(mockedService.GetSessionCalls())
+func (mock *ServiceMock) GetSessionCalls() []struct {
+	ExtraNonce1 int64
+} {
+	var calls []struct {
+		ExtraNonce1 int64
+	}
+	lockServiceMockGetSession.RLock()
+	calls = mock.calls.GetSession
+	lockServiceMockGetSession.RUnlock()
+	return calls
+}
+
+// GetSessions calls GetSessionsFunc.
+func (mock *ServiceMock) GetSessions() []*service.Session {
+	if mock.GetSessionsFunc == nil {
+		panic("ServiceMock.GetSessionsFunc: method is nil but Service.GetSessions was just called")
+	}
+	callInfo := struct {
+	}{}
+	lockServiceMockGetSessions.Lock()
+	mock.calls.GetSessions = append(mock.calls.GetSessions, callInfo)
+	lockServiceMockGetSessions.Unlock()
+	return mock.GetSessionsFunc()
+}
+
+// GetSessionsCalls gets all the calls that were made to GetSessions.
+// Check the length with:
+//     len(mockedService.GetSessionsCalls())
+func (mock *ServiceMock) GetSessionsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	lockServiceMockGetSessions.RLock()
+	calls = mock.calls.GetSessions
+	lockServiceMockGetSessions.RUnlock()
+	return calls
+}
+
 // Health calls HealthFunc.
 func (mock *ServiceMock) Health() *service.HealthResponse {
 	if mock.HealthFunc == nil {
@@ -121,6 +329,72 @@ func (mock *ServiceMock) HealthCalls() []struct {
 	return calls
 }
 
+// Ready calls ReadyFunc.
+func (mock *ServiceMock) Ready(ctx context.Context) *service.ReadinessResponse {
+	if mock.ReadyFunc == nil {
+		panic("ServiceMock.ReadyFunc: method is nil but Service.Ready was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	lockServiceMockReady.Lock()
+	mock.calls.Ready = append(mock.calls.Ready, callInfo)
+	lockServiceMockReady.Unlock()
+	return mock.ReadyFunc(ctx)
+}
+
+// ReadyCalls gets all the calls that were made to Ready.
+// Check the length with:
+//     len(mockedService.ReadyCalls())
+func (mock *ServiceMock) ReadyCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	lockServiceMockReady.RLock()
+	calls = mock.calls.Ready
+	lockServiceMockReady.RUnlock()
+	return calls
+}
+
+// RunTCPConnection calls RunTCPConnectionFunc.
+func (mock *ServiceMock) RunTCPConnection(ctx context.Context, conn net.Conn) {
+	if mock.RunTCPConnectionFunc == nil {
+		panic("ServiceMock.RunTCPConnectionFunc: method is nil but Service.RunTCPConnection was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Conn net.Conn
+	}{
+		Ctx:  ctx,
+		Conn: conn,
+	}
+	lockServiceMockRunTCPConnection.Lock()
+	mock.calls.RunTCPConnection = append(mock.calls.RunTCPConnection, callInfo)
+	lockServiceMockRunTCPConnection.Unlock()
+	mock.RunTCPConnectionFunc(ctx, conn)
+}
+
+// RunTCPConnectionCalls gets all the calls that were made to RunTCPConnection.
+// Check the length with:
+//     len(mockedService.RunTCPConnectionCalls())
+func (mock *ServiceMock) RunTCPConnectionCalls() []struct {
+	Ctx  context.Context
+	Conn net.Conn
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Conn net.Conn
+	}
+	lockServiceMockRunTCPConnection.RLock()
+	calls = mock.calls.RunTCPConnection
+	lockServiceMockRunTCPConnection.RUnlock()
+	return calls
+}
+
 // RunWebsocketConnection calls RunWebsocketConnectionFunc.
 func (mock *ServiceMock) RunWebsocketConnection(ctx context.Context, conn *websocket.Conn) {
 	if mock.RunWebsocketConnectionFunc == nil {
@@ -155,3 +429,42 @@ func (mock *ServiceMock) RunWebsocketConnectionCalls() []struct {
 	lockServiceMockRunWebsocketConnection.RUnlock()
 	return calls
 }
+
+// SetExtraNonce calls SetExtraNonceFunc.
+func (mock *ServiceMock) SetExtraNonce(extraNonce1 int64, newExtraNonce1 int64, extraNonce2 int64) (*service.Session, *service.AppError) {
+	if mock.SetExtraNonceFunc == nil {
+		panic("ServiceMock.SetExtraNonceFunc: method is nil but Service.SetExtraNonce was just called")
+	}
+	callInfo := struct {
+		ExtraNonce1    int64
+		NewExtraNonce1 int64
+		ExtraNonce2    int64
+	}{
+		ExtraNonce1:    extraNonce1,
+		NewExtraNonce1: newExtraNonce1,
+		ExtraNonce2:    extraNonce2,
+	}
+	lockServiceMockSetExtraNonce.Lock()
+	mock.calls.SetExtraNonce = append(mock.calls.SetExtraNonce, callInfo)
+	lockServiceMockSetExtraNonce.Unlock()
+	return mock.SetExtraNonceFunc(extraNonce1, newExtraNonce1, extraNonce2)
+}
+
+// SetExtraNonceCalls gets all the calls that were made to SetExtraNonce.
+// Check the length with:
+//     len(mockedService.SetExtraNonceCalls())
+func (mock *ServiceMock) SetExtraNonceCalls() []struct {
+	ExtraNonce1    int64
+	NewExtraNonce1 int64
+	ExtraNonce2    int64
+} {
+	var calls []struct {
+		ExtraNonce1    int64
+		NewExtraNonce1 int64
+		ExtraNonce2    int64
+	}
+	lockServiceMockSetExtraNonce.RLock()
+	calls = mock.calls.SetExtraNonce
+	lockServiceMockSetExtraNonce.RUnlock()
+	return calls
+}
@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"net"
+	"stratum-server/service"
+	"time"
+)
+
+const (
+	// delay before accepting again after a temporary error
+	acceptRetryDelay = 50 * time.Millisecond
+)
+
+// ServeTCP: accepts stratum+tcp connections until the listener is closed
+func ServeTCP(ln net.Listener, svc service.Service) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				log.Printf("temporary error accepting tcp conn: %v", err)
+				time.Sleep(acceptRetryDelay)
+				continue
+			}
+			return err
+		}
+
+		log.Printf("tcp conn accepted from %s", conn.RemoteAddr())
+		svc.RunTCPConnection(context.Background(), conn)
+	}
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeTCP(t *testing.T) {
+	accepted := make(chan net.Conn, 1)
+	svc := &ServiceMock{
+		RunTCPConnectionFunc: func(ctx context.Context, conn net.Conn) {
+			accepted <- conn
+		},
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeTCP(ln, svc)
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	select {
+	case c := <-accepted:
+		assert.Equal(t, conn.LocalAddr().String(), c.RemoteAddr().String())
+		c.Close()
+	case <-time.After(time.Second):
+		t.Fatal("connection not handed to the service")
+	}
+
+	ln.Close()
+	assert.Error(t, <-served)
+	assert.Len(t, svc.RunTCPConnectionCalls(), 1)
+}
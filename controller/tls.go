@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"stratum-server/config"
+	"sync"
+)
+
+// CertReloader keeps the TLS certificates in memory, so that they can be rotated without restarting the listeners.
+type CertReloader struct {
+	cfg       config.TLSConfig
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// NewCertReloader: loads the configured certificates
+func NewCertReloader(cfg config.TLSConfig) (*CertReloader, error) {
+	r := &CertReloader{
+		cfg: cfg,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload: reads the certificates from disk again. Current ones are kept when there's an error
+func (r *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("error loading certificate: %v", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("error reading client CA: %v", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no valid certificates found in client CA file %s", r.cfg.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	return nil
+}
+
+// TLSConfig: returns a config that always uses the last loaded certificates. GetCertificate is also set, since
+// http.Server.ServeTLS only skips loading the certificate files when the config has one
+func (r *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetCertificate:     r.getCertificate,
+		GetConfigForClient: r.getConfigForClient,
+	}
+}
+
+func (r *CertReloader) getCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *CertReloader) getConfigForClient(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*r.cert},
+	}
+	if r.clientCAs != nil {
+		c.ClientCAs = r.clientCAs
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return c, nil
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"stratum-server/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertReloader_Reload(t *testing.T) {
+	tests := []struct {
+		name  string
+		serve func(ln net.Listener, tlsConfig *tls.Config)
+	}{
+		{
+			name: "TLS TCP listener",
+			serve: func(ln net.Listener, tlsConfig *tls.Config) {
+				tlsLn := tls.NewListener(ln, tlsConfig)
+				for {
+					conn, err := tlsLn.Accept()
+					if err != nil {
+						return
+					}
+					_ = conn.(*tls.Conn).Handshake()
+					conn.Close()
+				}
+			},
+		},
+		{
+			name: "HTTPS listener",
+			serve: func(ln net.Listener, tlsConfig *tls.Config) {
+				srv := &http.Server{TLSConfig: tlsConfig}
+				_ = srv.ServeTLS(ln, "", "")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := config.TLSConfig{
+				CertFile: filepath.Join(dir, "cert.pem"),
+				KeyFile:  filepath.Join(dir, "key.pem"),
+			}
+
+			writeCertificate(t, cfg, "first")
+			r, err := NewCertReloader(cfg)
+			assert.NoError(t, err)
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NoError(t, err)
+			defer ln.Close()
+			go tt.serve(ln, r.TLSConfig())
+
+			assert.Equal(t, "first", serverCommonName(t, ln.Addr()))
+
+			writeCertificate(t, cfg, "second")
+			assert.Equal(t, "first", serverCommonName(t, ln.Addr()))
+			assert.NoError(t, r.Reload())
+			assert.Equal(t, "second", serverCommonName(t, ln.Addr()))
+
+			// current certificate is kept when reloading fails
+			assert.NoError(t, ioutil.WriteFile(cfg.CertFile, []byte("invalid"), 0600))
+			assert.Error(t, r.Reload())
+			assert.Equal(t, "second", serverCommonName(t, ln.Addr()))
+		})
+	}
+}
+
+func serverCommonName(t *testing.T, addr net.Addr) string {
+	conn, err := tls.Dial("tcp", addr.String(), &tls.Config{InsecureSkipVerify: true})
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func writeCertificate(t *testing.T, cfg config.TLSConfig, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(cfg.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, ioutil.WriteFile(cfg.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
@@ -0,0 +1,10 @@
+CREATE TABLE public.blocks (
+id SERIAL PRIMARY KEY,
+height BIGINT NOT NULL,
+hash VARCHAR(64) NOT NULL,
+extra_nonce_1 INT NOT NULL,
+subscriber VARCHAR(255) NOT NULL,
+worker VARCHAR(255) NOT NULL,
+result VARCHAR(255) NOT NULL,
+created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now() NOT NULL
+);
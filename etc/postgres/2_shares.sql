@@ -0,0 +1,15 @@
+CREATE TABLE public.shares (
+id BIGSERIAL PRIMARY KEY,
+extra_nonce_1 INT NOT NULL,
+account VARCHAR(255) NOT NULL,
+worker VARCHAR(255) NOT NULL,
+difficulty DOUBLE PRECISION NOT NULL,
+job_id VARCHAR(255) NOT NULL,
+result VARCHAR(16) NOT NULL,
+reject_reason VARCHAR(255) NOT NULL DEFAULT '',
+created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now() NOT NULL
+);
+
+CREATE INDEX shares_extra_nonce_1_worker_idx ON public.shares (extra_nonce_1, worker);
+CREATE INDEX shares_created_at_idx ON public.shares (created_at);
+CREATE INDEX shares_account_idx ON public.shares (account);
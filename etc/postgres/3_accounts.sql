@@ -0,0 +1,8 @@
+CREATE TABLE public.accounts (
+id SERIAL PRIMARY KEY,
+name VARCHAR(255) NOT NULL UNIQUE,
+password_hash VARCHAR(255) NOT NULL,
+-- where the balance is paid, accounts named after their address don't need it
+payout_address VARCHAR(255),
+created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now() NOT NULL
+);
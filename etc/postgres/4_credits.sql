@@ -0,0 +1,17 @@
+CREATE TABLE public.credits (
+id BIGSERIAL PRIMARY KEY,
+account VARCHAR(255) NOT NULL,
+-- empty for payment debits
+block_height BIGINT,
+-- empty for the credits granted at share acceptance time
+block_hash VARCHAR(64),
+scheme VARCHAR(16) NOT NULL,
+-- in satoshis, per share credits are fractional and payment debits are negative
+amount NUMERIC(24, 8) NOT NULL,
+payment_id BIGINT,
+created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now() NOT NULL,
+UNIQUE (block_hash, account)
+);
+
+CREATE INDEX credits_account_idx ON public.credits (account);
+CREATE INDEX credits_payment_id_idx ON public.credits (payment_id);
@@ -0,0 +1,13 @@
+CREATE TABLE public.payments (
+id BIGSERIAL PRIMARY KEY,
+-- pending until the wallet returns the txid, pending payments are never retried automatically
+status VARCHAR(16) NOT NULL,
+txid VARCHAR(64),
+-- [{"account": ..., "address": ..., "amount": ...}] with amounts in satoshis
+amounts JSONB NOT NULL,
+error TEXT NOT NULL DEFAULT '',
+created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now() NOT NULL,
+updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now() NOT NULL
+);
+
+CREATE INDEX payments_status_idx ON public.payments (status);
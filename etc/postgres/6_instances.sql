@@ -0,0 +1,10 @@
+CREATE TABLE public.instances (
+id VARCHAR(255) PRIMARY KEY,
+started_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now() NOT NULL,
+heartbeat_at TIMESTAMP WITHOUT TIME ZONE DEFAULT now() NOT NULL
+);
+
+-- instance holding the session, freed when it stops heartbeating
+ALTER TABLE public.subscriptions ADD COLUMN instance_id VARCHAR(255);
+
+CREATE INDEX subscriptions_instance_id_idx ON public.subscriptions (instance_id) WHERE active_session;
\ No newline at end of file
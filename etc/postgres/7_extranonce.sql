@@ -0,0 +1,7 @@
+-- extraNonce1 values are 4 bytes, the ones above the SERIAL range are reserved for reassignments
+ALTER SEQUENCE public.subscriptions_extra_nonce_1_seq AS INTEGER MAXVALUE 2147483647;
+ALTER TABLE public.subscriptions ALTER COLUMN extra_nonce_1 TYPE BIGINT;
+ALTER TABLE public.subscriptions ADD CONSTRAINT subscriptions_extra_nonce_1_key UNIQUE (extra_nonce_1);
+
+ALTER TABLE public.blocks ALTER COLUMN extra_nonce_1 TYPE BIGINT;
+ALTER TABLE public.shares ALTER COLUMN extra_nonce_1 TYPE BIGINT;
\ No newline at end of file
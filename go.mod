@@ -1,6 +1,6 @@
 module stratum-server
 
-go 1.15
+go 1.16
 
 require (
 	github.com/go-chi/chi v1.5.4
@@ -8,8 +8,10 @@ require (
 	github.com/gorilla/websocket v1.4.2
 	github.com/joho/godotenv v1.3.0
 	github.com/lib/pq v1.10.2
-	github.com/pkg/errors v0.8.1
-	github.com/prometheus/common v0.4.0
+	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.11.0
+	github.com/prometheus/common v0.26.0
 	github.com/spf13/viper v1.7.1
-	github.com/stretchr/testify v1.3.0
+	github.com/stretchr/testify v1.4.0
+	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a
 )
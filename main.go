@@ -2,14 +2,19 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"stratum-server/config"
 	"stratum-server/controller"
+	"stratum-server/metrics"
+	"stratum-server/payout"
 	"stratum-server/repository"
 	"stratum-server/service"
+	"stratum-server/template"
 	"syscall"
 	"time"
 
@@ -32,26 +37,139 @@ func main() {
 		log.Fatalf("failed to init config: %v", err.Error())
 	}
 
-	postgres := repository.NewRepository(cfg.PostgreSQLConfig)
-	svc := service.NewService(postgres, cfg.SubscriptionsTable)
-	handler := controller.NewHandler(svc)
+	prometheus := metrics.NewPrometheus()
+	postgres := repository.NewRepository(cfg.PostgreSQLConfig, prometheus)
+	var templateSource template.Source
+	if cfg.NodeConfig.RPCURL != "" {
+		templateSource = template.NewRPCSource(template.NewClient(cfg.NodeConfig), cfg.NodeConfig.PollInterval)
+	}
+	svc := service.NewService(postgres, cfg, templateSource, prometheus)
+	handler := controller.NewHandler(svc, cfg.AdminAPIToken, prometheus.Handler())
+
+	// sessions orphaned by a previous crash are freed before accepting connections
+	if err := svc.RegisterInstance(); err != nil {
+		log.Fatalf("failed to register instance: %v", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go svc.RunHeartbeat(ctx)
+	go svc.RunVardiff(ctx)
+	shareWriterDone := make(chan struct{})
+	go func() {
+		svc.RunShareWriter(ctx)
+		close(shareWriterDone)
+	}()
+	go svc.RunMetrics(ctx)
+	if templateSource != nil {
+		go svc.RunTemplates(ctx)
+	}
+	if cfg.WalletRPCURL != "" && cfg.Mode == config.MiningModePool {
+		wallet := payout.NewRPCWallet(template.NewClient(config.NodeConfig{
+			RPCURL:      cfg.WalletRPCURL,
+			RPCUser:     cfg.WalletRPCUser,
+			RPCPassword: cfg.WalletRPCPassword,
+		}))
+		go payout.NewPayer(postgres, wallet, cfg).Run(ctx)
+	}
 
 	server := &http.Server{
 		Addr:    ":" + cfg.HTTPPort,
 		Handler: handler,
 	}
+	servers := []*http.Server{server}
+
+	var listeners []net.Listener
+	if cfg.TCPPort != "" {
+		listeners = append(listeners, startTCPListener(cfg.TCPPort, nil, svc))
+	}
+
+	if cfg.TLSConfig.Enabled() {
+		certReloader, err := controller.NewCertReloader(cfg.TLSConfig)
+		if err != nil {
+			log.Fatalf("failed to load TLS certificates: %s", err.Error())
+		}
+
+		if cfg.TLSConfig.TCPPort != "" {
+			listeners = append(listeners, startTCPListener(cfg.TLSConfig.TCPPort, certReloader.TLSConfig(), svc))
+		}
+		if cfg.TLSConfig.HTTPSPort != "" {
+			tlsServer := &http.Server{
+				Addr:      ":" + cfg.TLSConfig.HTTPSPort,
+				Handler:   handler,
+				TLSConfig: certReloader.TLSConfig(),
+			}
+			servers = append(servers, tlsServer)
+			go func() {
+				log.Printf("HTTPS listener started on :%s @ %s", cfg.TLSConfig.HTTPSPort, time.Now().Format(time.RFC3339))
+				if err := tlsServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+					log.Fatalf("failed to start https server: %s", err.Error())
+				}
+			}()
+		}
+
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		go func() {
+			for range reload {
+				if err := certReloader.Reload(); err != nil {
+					log.Printf("failed to reload TLS certificates: %s", err.Error())
+					continue
+				}
+				log.Print("TLS certificates reloaded")
+			}
+		}()
+	}
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	shutdown := make(chan struct{})
 	go func() {
 		<-signals
-		if err := server.Shutdown(context.Background()); err != nil {
-			log.Fatalf("error on server shutdown: %s", err.Error())
+		log.Print("shutting down, no more connections are accepted")
+		for _, ln := range listeners {
+			ln.Close()
+		}
+		// hijacked websocket connections are not closed by Shutdown, they're drained below
+		for _, srv := range servers {
+			if err := srv.Shutdown(context.Background()); err != nil {
+				log.Fatalf("error on server shutdown: %s", err.Error())
+			}
+		}
+		if err := svc.Drain(context.Background()); err != nil {
+			log.Printf("error draining sessions: %s", err.Error())
 		}
+
+		// the queued shares are persisted before exiting
+		cancel()
+		<-shareWriterDone
+		close(shutdown)
 	}()
 
 	log.Printf("HTTP listener started on :%s @ %s", cfg.HTTPPort, time.Now().Format(time.RFC3339))
-	if err := server.ListenAndServe(); err != nil {
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("failed to start http server: %s", err.Error())
 	}
+	<-shutdown
+}
+
+// startTCPListener: serves stratum+tcp, or stratum+ssl when a TLS config is provided
+func startTCPListener(port string, tlsConfig *tls.Config, svc service.Service) net.Listener {
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatalf("failed to start tcp listener: %s", err.Error())
+	}
+
+	name := "TCP"
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+		name = "TLS TCP"
+	}
+
+	go func() {
+		if err := controller.ServeTCP(ln, svc); err != nil {
+			log.Printf("%s listener stopped: %s", name, err.Error())
+		}
+	}()
+	log.Printf("%s listener started on :%s @ %s", name, port, time.Now().Format(time.RFC3339))
+	return ln
 }
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"time"
+)
+
+const (
+	// results of the handled requests and submitted shares
+	ResultOK       = "ok"
+	ResultError    = "error"
+	ResultAccepted = "accepted"
+	ResultRejected = "rejected"
+)
+
+// Metrics describes the instrumentation of the server, so that it can be replaced in tests.
+type Metrics interface {
+	// ConnectionOpened: counts a new connection for the given transport
+	ConnectionOpened(transport string)
+	// ConnectionClosed: discounts a connection of the given transport
+	ConnectionClosed(transport string)
+	// RequestHandled: counts a handled request by method and result, code is 0 for successful requests
+	RequestHandled(method string, result string, code int)
+	// ShareSubmitted: counts a submitted share and its difficulty by result
+	ShareSubmitted(result string, difficulty float64)
+	// ShareDropped: counts a share that couldn't be queued to be persisted
+	ShareDropped()
+	// SetHashrate: sets the estimated pool hashrate, in hashes per second
+	SetHashrate(hashrate float64)
+	// SetQueueDepth: sets the amount of messages waiting to be written in every connection
+	SetQueueDepth(depth int)
+	// DBQueryObserved: observes the duration of a DB operation
+	DBQueryObserved(operation string, duration time.Duration)
+}
+
+type noop struct{}
+
+// NewNoop creates metrics that are discarded, meant for tests and disabled instrumentation.
+func NewNoop() Metrics {
+	return noop{}
+}
+
+func (noop) ConnectionOpened(string)               {}
+func (noop) ConnectionClosed(string)               {}
+func (noop) RequestHandled(string, string, int)    {}
+func (noop) ShareSubmitted(string, float64)        {}
+func (noop) ShareDropped()                         {}
+func (noop) SetHashrate(float64)                   {}
+func (noop) SetQueueDepth(int)                     {}
+func (noop) DBQueryObserved(string, time.Duration) {}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "stratum"
+)
+
+// Prometheus exposes the metrics in the Prometheus text format.
+type Prometheus struct {
+	registry         *prometheus.Registry
+	connections      *prometheus.GaugeVec
+	requests         *prometheus.CounterVec
+	shares           *prometheus.CounterVec
+	shareDifficulty  *prometheus.CounterVec
+	sharesDropped    prometheus.Counter
+	hashrate         prometheus.Gauge
+	queueDepth       prometheus.Gauge
+	dbQueryDurations *prometheus.HistogramVec
+}
+
+// NewPrometheus creates the metrics in their own registry, along with the Go runtime and process ones.
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		connections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "connections",
+			Help:      "Active connections by transport.",
+		}, []string{"transport"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Handled requests by method, result and error code.",
+		}, []string{"method", "result", "code"}),
+		shares: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shares_total",
+			Help:      "Submitted shares by result.",
+		}, []string{"result"}),
+		shareDifficulty: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "share_difficulty_total",
+			Help:      "Sum of the difficulty of the submitted shares by result.",
+		}, []string{"result"}),
+		sharesDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shares_dropped_total",
+			Help:      "Shares that couldn't be queued to be persisted.",
+		}),
+		hashrate: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "hashrate",
+			Help:      "Estimated pool hashrate, in hashes per second.",
+		}),
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "write_queue_depth",
+			Help:      "Messages waiting to be written in every connection.",
+		}),
+		dbQueryDurations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Duration of the DB operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+	}
+
+	p.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		p.connections,
+		p.requests,
+		p.shares,
+		p.shareDifficulty,
+		p.sharesDropped,
+		p.hashrate,
+		p.queueDepth,
+		p.dbQueryDurations,
+	)
+	return p
+}
+
+// Handler: serves the registered metrics
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+func (p *Prometheus) ConnectionOpened(transport string) {
+	p.connections.WithLabelValues(transport).Inc()
+}
+
+func (p *Prometheus) ConnectionClosed(transport string) {
+	p.connections.WithLabelValues(transport).Dec()
+}
+
+func (p *Prometheus) RequestHandled(method string, result string, code int) {
+	p.requests.WithLabelValues(method, result, strconv.Itoa(code)).Inc()
+}
+
+func (p *Prometheus) ShareSubmitted(result string, difficulty float64) {
+	p.shares.WithLabelValues(result).Inc()
+	p.shareDifficulty.WithLabelValues(result).Add(difficulty)
+}
+
+func (p *Prometheus) ShareDropped() {
+	p.sharesDropped.Inc()
+}
+
+func (p *Prometheus) SetHashrate(hashrate float64) {
+	p.hashrate.Set(hashrate)
+}
+
+func (p *Prometheus) SetQueueDepth(depth int) {
+	p.queueDepth.Set(float64(depth))
+}
+
+func (p *Prometheus) DBQueryObserved(operation string, duration time.Duration) {
+	p.dbQueryDurations.WithLabelValues(operation).Observe(duration.Seconds())
+}
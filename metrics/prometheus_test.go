@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheus_Handler(t *testing.T) {
+	p := NewPrometheus()
+	p.ConnectionOpened("tcp")
+	p.ConnectionOpened("tcp")
+	p.ConnectionOpened("ws")
+	p.ConnectionClosed("tcp")
+	p.RequestHandled("mining.submit", ResultOK, 0)
+	p.RequestHandled("mining.submit", ResultError, 23)
+	p.RequestHandled("mining.submit", ResultError, 23)
+	p.ShareSubmitted(ResultAccepted, 512)
+	p.ShareSubmitted(ResultAccepted, 256)
+	p.ShareSubmitted(ResultRejected, 512)
+	p.ShareDropped()
+	p.SetHashrate(1.5e12)
+	p.SetQueueDepth(3)
+	p.DBQueryObserved("insert", 20*time.Millisecond)
+
+	rr := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	body, err := ioutil.ReadAll(rr.Body)
+	assert.NoError(t, err)
+	for _, line := range []string{
+		`stratum_connections{transport="tcp"} 1`,
+		`stratum_connections{transport="ws"} 1`,
+		`stratum_requests_total{code="0",method="mining.submit",result="ok"} 1`,
+		`stratum_requests_total{code="23",method="mining.submit",result="error"} 2`,
+		`stratum_shares_total{result="accepted"} 2`,
+		`stratum_share_difficulty_total{result="accepted"} 768`,
+		`stratum_share_difficulty_total{result="rejected"} 512`,
+		`stratum_shares_dropped_total 1`,
+		`stratum_hashrate 1.5e+12`,
+		`stratum_write_queue_depth 3`,
+		`stratum_db_query_duration_seconds_count{operation="insert"} 1`,
+		`stratum_db_query_duration_seconds_bucket{operation="insert",le="0.025"} 1`,
+	} {
+		assert.Contains(t, string(body), line)
+	}
+}
@@ -0,0 +1,57 @@
+package payout
+
+import (
+	"fmt"
+	"log"
+	"stratum-server/config"
+	"stratum-server/repository"
+)
+
+// ledger persists the credits owed to the accounts.
+type ledger struct {
+	creditsTable config.PostgreSQLTableConfig
+	scheme       string
+}
+
+func newLedger(cfg *config.Config, scheme string) *ledger {
+	return &ledger{
+		creditsTable: cfg.CreditsTable,
+		scheme:       scheme,
+	}
+}
+
+// CreateCredits: persists the credits in a single batch insert, which is run in a transaction when it's split, so they
+// are either all stored or none
+func (l *ledger) CreateCredits(repo repository.Repository, credits []Credit) error {
+	if len(credits) == 0 {
+		return nil
+	}
+
+	sqlStatement := fmt.Sprintf(`
+	INSERT INTO %s.%s (account, block_height, block_hash, scheme, amount)`, l.creditsTable.Schema, l.creditsTable.Name)
+
+	rows := make([][]interface{}, 0, len(credits))
+	for _, c := range credits {
+		var blockHash interface{}
+		if c.BlockHash != "" {
+			blockHash = c.BlockHash
+		}
+		rows = append(rows, []interface{}{
+			c.Account,
+			c.Height,
+			blockHash,
+			l.scheme,
+			c.Amount,
+		})
+	}
+
+	if err := repo.BatchInsert(repository.BatchInsertRequest{
+		Query: sqlStatement,
+		Rows:  rows,
+	}); err != nil {
+		log.Printf("error creating credits: %v", err)
+		return err
+	}
+
+	return nil
+}
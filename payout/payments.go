@@ -0,0 +1,275 @@
+package payout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"stratum-server/bitcoin"
+	"stratum-server/config"
+	"stratum-server/repository"
+	"stratum-server/template"
+	"time"
+)
+
+const (
+	paymentPending = "pending"
+	paymentSent    = "sent"
+	paymentFailed  = "failed"
+
+	// scheme of the debits created for every payment
+	paymentScheme = "payment"
+	sendTimeout   = time.Minute
+
+	// advisory lock serializing the payments of every instance
+	paymentsLockKey int64 = 0x7061796d656e7473
+)
+
+// Payment represents the amount paid to an account, in satoshis.
+type Payment struct {
+	Account string `json:"account"`
+	Address string `json:"address"`
+	Amount  int64  `json:"amount"`
+}
+
+// Payer periodically pays the balances above the threshold in a single batched transaction.
+type Payer struct {
+	repository    repository.Repository
+	wallet        Wallet
+	creditsTable  config.PostgreSQLTableConfig
+	accountsTable config.PostgreSQLTableConfig
+	paymentsTable config.PostgreSQLTableConfig
+	network       string
+	interval      time.Duration
+	threshold     int64
+}
+
+// NewPayer creates a payer sending the payments through the given wallet.
+func NewPayer(repository repository.Repository, wallet Wallet, cfg *config.Config) *Payer {
+	return &Payer{
+		repository:    repository,
+		wallet:        wallet,
+		creditsTable:  cfg.CreditsTable,
+		accountsTable: cfg.AccountsTable,
+		paymentsTable: cfg.PaymentsTable,
+		network:       cfg.Network,
+		interval:      cfg.PaymentConfig.Interval,
+		threshold:     cfg.Threshold,
+	}
+}
+
+// Run: pays the balances on every interval until the context is done
+func (p *Payer) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Pay(ctx); err != nil {
+				log.Printf("[payment] error paying balances: %v", err)
+			}
+		}
+	}
+}
+
+// Pay: pays every balance above the threshold. The balances are debited together with the pending payment
+// before sending it, so a crash at any point never pays them twice. Payments rejected by the wallet are
+// reverted, while the ones with an unknown outcome are left pending to be checked manually.
+// The balances are read and debited in a transaction holding an advisory lock, so that concurrent instances
+// never pay the same credits: the second one reads the balances once they're debited.
+func (p *Payer) Pay(ctx context.Context) error {
+	var id int64
+	var payments []Payment
+	amounts := make(map[string]int64)
+	if err := p.repository.Transaction(func(tx repository.Repository) error {
+		if err := p.lock(tx); err != nil {
+			return err
+		}
+		balances, err := p.getBalances(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range balances {
+			if _, err := bitcoin.DecodeAddress(b.Address, p.network); err != nil {
+				log.Printf("[payment] skipping account %s with invalid address %s: %v", b.Account, b.Address, err)
+				continue
+			}
+			payments = append(payments, b)
+			amounts[b.Address] += b.Amount
+		}
+		if len(payments) == 0 {
+			return nil
+		}
+
+		id, err = p.createPayment(tx, payments)
+		return err
+	}); err != nil {
+		return err
+	}
+	if len(payments) == 0 {
+		return nil
+	}
+	log.Printf("[payment] sending payment %d to %d accounts", id, len(payments))
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	txid, err := p.wallet.SendMany(sendCtx, amounts, fmt.Sprintf("payment %d", id))
+	if err != nil {
+		var rpcErr *template.RPCError
+		if errors.As(err, &rpcErr) {
+			log.Printf("[payment] payment %d rejected by the wallet: %v", id, err)
+			if err := p.revertPayment(id, rpcErr.Message); err != nil {
+				return err
+			}
+			return rpcErr
+		}
+		log.Printf("[payment] payment %d left pending, check the wallet before retrying: %v", id, err)
+		return err
+	}
+
+	log.Printf("[payment] payment %d sent in tx %s", id, txid)
+	return p.completePayment(id, txid)
+}
+
+// lock: waits for the payments of other instances, the lock is released with the transaction
+func (p *Payer) lock(tx repository.Repository) error {
+	sqlStatement := `SELECT true FROM pg_advisory_xact_lock($1)`
+
+	var locked bool
+	if err := tx.Query(repository.QueryRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			paymentsLockKey,
+		},
+	}, &locked); err != nil {
+		log.Printf("error locking payments: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// getBalances: returns the whole satoshis owed to every account above the threshold
+func (p *Payer) getBalances(tx repository.Repository) ([]Payment, error) {
+	sqlStatement := fmt.Sprintf(`
+	SELECT c.account, COALESCE(a.payout_address, c.account), FLOOR(SUM(c.amount))::BIGINT
+	FROM %s.%s AS c
+	LEFT JOIN %s.%s AS a ON a.name = c.account
+	GROUP BY c.account, a.payout_address
+	HAVING FLOOR(SUM(c.amount)) >= $1
+	ORDER BY c.account`,
+		p.creditsTable.Schema, p.creditsTable.Name, p.accountsTable.Schema, p.accountsTable.Name)
+
+	var balances []Payment
+	if err := tx.QueryRows(repository.QueryRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			p.threshold,
+		},
+	}, func(scan repository.RowScanner) error {
+		b := Payment{}
+		if err := scan(&b.Account, &b.Address, &b.Amount); err != nil {
+			return err
+		}
+		balances = append(balances, b)
+		return nil
+	}); err != nil {
+		log.Printf("error getting balances: %v", err)
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+// createPayment: stores the pending payment and debits the balances in a single statement
+func (p *Payer) createPayment(tx repository.Repository, payments []Payment) (int64, error) {
+	amounts, err := json.Marshal(payments)
+	if err != nil {
+		return 0, err
+	}
+
+	sqlStatement := fmt.Sprintf(`
+	WITH payment AS (
+		INSERT INTO %s.%s (status, amounts)
+		VALUES ($1, $2)
+		RETURNING id
+	), debits AS (
+		INSERT INTO %s.%s (account, scheme, amount, payment_id)
+		SELECT d.account, $3, -d.amount, payment.id
+		FROM payment, jsonb_to_recordset($2) AS d(account VARCHAR, amount BIGINT)
+	)
+	SELECT id FROM payment`,
+		p.paymentsTable.Schema, p.paymentsTable.Name, p.creditsTable.Schema, p.creditsTable.Name)
+
+	var id int64
+	if err := tx.Insert(repository.InsertRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			paymentPending,
+			string(amounts),
+			paymentScheme,
+		},
+	}, &id); err != nil {
+		log.Printf("error creating payment: %v", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (p *Payer) completePayment(id int64, txid string) error {
+	sqlStatement := fmt.Sprintf(`
+	UPDATE %s.%s
+	SET status = $2, txid = $3, updated_at = now()
+	WHERE id = $1
+	RETURNING id`, p.paymentsTable.Schema, p.paymentsTable.Name)
+
+	if err := p.repository.Update(repository.UpdateRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			id,
+			paymentSent,
+			txid,
+		},
+	}, &id); err != nil {
+		log.Printf("error completing payment %d with tx %s: %v", id, txid, err)
+		return err
+	}
+
+	return nil
+}
+
+// revertPayment: marks the payment as failed and removes its debits in a single statement
+func (p *Payer) revertPayment(id int64, reason string) error {
+	sqlStatement := fmt.Sprintf(`
+	WITH payment AS (
+		UPDATE %s.%s
+		SET status = $2, error = $3, updated_at = now()
+		WHERE id = $1
+		RETURNING id
+	), debits AS (
+		DELETE FROM %s.%s
+		WHERE payment_id IN (SELECT id FROM payment)
+	)
+	SELECT id FROM payment`,
+		p.paymentsTable.Schema, p.paymentsTable.Name, p.creditsTable.Schema, p.creditsTable.Name)
+
+	if err := p.repository.Update(repository.UpdateRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			id,
+			paymentFailed,
+			reason,
+		},
+	}, &id); err != nil {
+		log.Printf("error reverting payment %d: %v", id, err)
+		return err
+	}
+
+	return nil
+}
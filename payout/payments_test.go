@@ -0,0 +1,238 @@
+package payout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"stratum-server/config"
+	"stratum-server/payout/payouttest"
+	"stratum-server/repository"
+	"stratum-server/template"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayer_Pay(t *testing.T) {
+	const (
+		aliceAddress = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+		bobAddress   = "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy"
+	)
+
+	tests := []struct {
+		name             string
+		balances         []Payment
+		balancesErr      error
+		walletErr        *template.RPCError
+		expectedDebits   []Payment
+		expectedPayments []map[string]json.Number
+		expectedUpdate   []interface{}
+		expectedError    error
+	}{
+		{
+			name: "pays every balance in a single transaction",
+			balances: []Payment{
+				{Account: "alice", Address: aliceAddress, Amount: 1500000},
+				{Account: "bob", Address: bobAddress, Amount: 100000000},
+			},
+			expectedDebits: []Payment{
+				{Account: "alice", Address: aliceAddress, Amount: 1500000},
+				{Account: "bob", Address: bobAddress, Amount: 100000000},
+			},
+			expectedPayments: []map[string]json.Number{
+				{aliceAddress: "0.01500000", bobAddress: "1.00000000"},
+			},
+			expectedUpdate: []interface{}{int64(7), paymentSent, payouttest.TxID(1)},
+		},
+		{
+			name: "accounts with the same address are paid together",
+			balances: []Payment{
+				{Account: "alice", Address: aliceAddress, Amount: 1000000},
+				{Account: "carol", Address: aliceAddress, Amount: 2000000},
+			},
+			expectedDebits: []Payment{
+				{Account: "alice", Address: aliceAddress, Amount: 1000000},
+				{Account: "carol", Address: aliceAddress, Amount: 2000000},
+			},
+			expectedPayments: []map[string]json.Number{
+				{aliceAddress: "0.03000000"},
+			},
+			expectedUpdate: []interface{}{int64(7), paymentSent, payouttest.TxID(1)},
+		},
+		{
+			name: "accounts with invalid addresses are skipped",
+			balances: []Payment{
+				{Account: "alice", Address: aliceAddress, Amount: 1500000},
+				{Account: "dave", Address: "dave", Amount: 1500000},
+			},
+			expectedDebits: []Payment{
+				{Account: "alice", Address: aliceAddress, Amount: 1500000},
+			},
+			expectedPayments: []map[string]json.Number{
+				{aliceAddress: "0.01500000"},
+			},
+			expectedUpdate: []interface{}{int64(7), paymentSent, payouttest.TxID(1)},
+		},
+		{
+			name: "no balances above the threshold",
+		},
+		{
+			name:          "error getting balances",
+			balancesErr:   fmt.Errorf("connection refused"),
+			expectedError: fmt.Errorf("connection refused"),
+		},
+		{
+			name: "payment rejected by the wallet is reverted",
+			balances: []Payment{
+				{Account: "alice", Address: aliceAddress, Amount: 1500000},
+			},
+			walletErr: &template.RPCError{Code: -6, Message: "Insufficient funds"},
+			expectedDebits: []Payment{
+				{Account: "alice", Address: aliceAddress, Amount: 1500000},
+			},
+			expectedUpdate: []interface{}{int64(7), paymentFailed, "Insufficient funds"},
+			expectedError:  &template.RPCError{Code: -6, Message: "Insufficient funds"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wallet := payouttest.NewFakeWallet()
+			defer wallet.Close()
+			wallet.SetError(tt.walletErr)
+
+			var debits []Payment
+			var update []interface{}
+			var locked bool
+			repo := &RepositoryMock{
+				QueryFunc: func(input repository.QueryRequest, destinationArgs ...interface{}) error {
+					assert.Equal(t, []interface{}{paymentsLockKey}, input.Args)
+					locked = true
+					return nil
+				},
+				QueryRowsFunc: func(input repository.QueryRequest, onRow func(scan repository.RowScanner) error) error {
+					assert.True(t, locked, "balances read without the payments lock")
+					assert.Equal(t, []interface{}{int64(1000000)}, input.Args)
+					if tt.balancesErr != nil {
+						return tt.balancesErr
+					}
+					for _, b := range tt.balances {
+						b := b
+						if err := onRow(func(destinationArgs ...interface{}) error {
+							*destinationArgs[0].(*string) = b.Account
+							*destinationArgs[1].(*string) = b.Address
+							*destinationArgs[2].(*int64) = b.Amount
+							return nil
+						}); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+				InsertFunc: func(input repository.InsertRequest, destinationArgs ...interface{}) error {
+					assert.Equal(t, paymentPending, input.Args[0])
+					assert.NoError(t, json.Unmarshal([]byte(input.Args[1].(string)), &debits))
+					*destinationArgs[0].(*int64) = 7
+					return nil
+				},
+				UpdateFunc: func(input repository.UpdateRequest, destinationArgs ...interface{}) error {
+					update = input.Args
+					return nil
+				},
+			}
+			repo.TransactionFunc = func(fn func(tx repository.Repository) error) error {
+				defer func() { locked = false }()
+				return fn(repo)
+			}
+			payer := NewPayer(repo, NewRPCWallet(template.NewClient(config.NodeConfig{RPCURL: wallet.URL()})), &config.Config{
+				Network: "mainnet",
+				PaymentConfig: config.PaymentConfig{
+					Threshold: 1000000,
+				},
+			})
+
+			err := payer.Pay(context.Background())
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedDebits, debits)
+			assert.Equal(t, tt.expectedPayments, paymentsOrNil(wallet.Payments()))
+			assert.Equal(t, tt.expectedUpdate, update)
+		})
+	}
+}
+
+// TestPayer_Pay_concurrent: two instances paying at once, the payments lock makes the second one read the debited
+// balances, so the credits are only paid once
+func TestPayer_Pay_concurrent(t *testing.T) {
+	const aliceAddress = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+
+	wallet := payouttest.NewFakeWallet()
+	defer wallet.Close()
+
+	// the advisory lock, held from the lock query until the transaction ends
+	var paymentsLock sync.Mutex
+	var ledgerMu sync.Mutex
+	balance := int64(1500000)
+	repo := &RepositoryMock{
+		QueryFunc: func(input repository.QueryRequest, destinationArgs ...interface{}) error {
+			paymentsLock.Lock()
+			return nil
+		},
+		QueryRowsFunc: func(input repository.QueryRequest, onRow func(scan repository.RowScanner) error) error {
+			ledgerMu.Lock()
+			defer ledgerMu.Unlock()
+			if balance < input.Args[0].(int64) {
+				return nil
+			}
+			return onRow(func(destinationArgs ...interface{}) error {
+				*destinationArgs[0].(*string) = "alice"
+				*destinationArgs[1].(*string) = aliceAddress
+				*destinationArgs[2].(*int64) = balance
+				return nil
+			})
+		},
+		InsertFunc: func(input repository.InsertRequest, destinationArgs ...interface{}) error {
+			var debits []Payment
+			assert.NoError(t, json.Unmarshal([]byte(input.Args[1].(string)), &debits))
+			ledgerMu.Lock()
+			defer ledgerMu.Unlock()
+			for _, d := range debits {
+				balance -= d.Amount
+			}
+			*destinationArgs[0].(*int64) = 7
+			return nil
+		},
+		UpdateFunc: func(input repository.UpdateRequest, destinationArgs ...interface{}) error {
+			return nil
+		},
+	}
+	repo.TransactionFunc = func(fn func(tx repository.Repository) error) error {
+		defer paymentsLock.Unlock()
+		return fn(repo)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			payer := NewPayer(repo, NewRPCWallet(template.NewClient(config.NodeConfig{RPCURL: wallet.URL()})), &config.Config{
+				Network: "mainnet",
+				PaymentConfig: config.PaymentConfig{
+					Threshold: 1000000,
+				},
+			})
+			assert.NoError(t, payer.Pay(context.Background()))
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, []map[string]json.Number{{aliceAddress: "0.01500000"}}, wallet.Payments())
+	assert.Equal(t, int64(0), balance)
+}
+
+func paymentsOrNil(payments []map[string]json.Number) []map[string]json.Number {
+	if len(payments) == 0 {
+		return nil
+	}
+	return payments
+}
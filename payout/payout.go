@@ -0,0 +1,64 @@
+package payout
+
+import (
+	"stratum-server/config"
+	"stratum-server/repository"
+	"time"
+)
+
+// Scheme describes how the rewards are split between the pool accounts.
+type Scheme interface {
+	// ShareCredit: returns the amount owed for an accepted share, 0 for schemes only paying on found blocks
+	ShareCredit(sh Share, r Round) float64
+	// CreateCredits: persists the given credits in the ledger through the given repository, so that they can be
+	// part of a transaction
+	CreateCredits(repo repository.Repository, credits []Credit) error
+	// BlockFound: credits the accounts for a block accepted by the network
+	BlockFound(b Block) error
+}
+
+// Block represents a block found by the pool.
+type Block struct {
+	Height int64
+	Hash   string
+	// subsidy plus fees, in satoshis
+	Reward  int64
+	FoundAt time.Time
+}
+
+// Round represents the block the shares are mined for.
+type Round struct {
+	Height            int64
+	NetworkDifficulty float64
+	// in satoshis
+	Subsidy int64
+	// expected transaction fees of the template, in satoshis
+	Fees int64
+}
+
+// Share represents an accepted share of an account.
+type Share struct {
+	Account    string
+	Difficulty float64
+}
+
+// Credit represents the amount owed to an account, in satoshis. Credits granted at share acceptance time
+// have no block hash.
+type Credit struct {
+	Account   string
+	Height    int64
+	BlockHash string
+	Amount    float64
+}
+
+// NewScheme creates the payout scheme selected in the config.
+func NewScheme(repository repository.Repository, cfg *config.Config) Scheme {
+	switch cfg.Scheme {
+	case config.PayoutSchemePPS:
+		return NewPPS(cfg)
+	case config.PayoutSchemeFPPS:
+		return NewFPPS(cfg)
+	default:
+		return NewPPLNS(repository, cfg)
+	}
+}
@@ -0,0 +1,105 @@
+package payouttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"stratum-server/template"
+	"sync"
+)
+
+// FakeWallet is an in-process bitcoind-compatible wallet RPC to be used in tests. It records every
+// sendmany call, returning sequential txids.
+type FakeWallet struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	payments []map[string]json.Number
+	err      *template.RPCError
+}
+
+type rpcRequest struct {
+	ID     interface{}       `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     interface{}        `json:"id"`
+	Result interface{}        `json:"result"`
+	Error  *template.RPCError `json:"error"`
+}
+
+// NewFakeWallet: starts a fake wallet
+func NewFakeWallet() *FakeWallet {
+	w := &FakeWallet{}
+	w.server = httptest.NewServer(http.HandlerFunc(w.serveHTTP))
+	return w
+}
+
+func (w *FakeWallet) URL() string {
+	return w.server.URL
+}
+
+func (w *FakeWallet) Close() {
+	w.server.Close()
+}
+
+// SetError: makes every following sendmany call fail with the given error, nil to succeed again
+func (w *FakeWallet) SetError(err *template.RPCError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+}
+
+// Payments: returns the amounts, in bitcoins by address, of every successful sendmany call
+func (w *FakeWallet) Payments() []map[string]json.Number {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]map[string]json.Number{}, w.payments...)
+}
+
+// TxID: returns the txid of the n-th payment, starting at 1
+func TxID(n int) string {
+	return fmt.Sprintf("%064x", n)
+}
+
+func (w *FakeWallet) serveHTTP(rw http.ResponseWriter, r *http.Request) {
+	req := &rpcRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res := &rpcResponse{ID: req.ID}
+	switch req.Method {
+	case "sendmany":
+		res.Result, res.Error = w.sendMany(req.Params)
+	default:
+		res.Error = &template.RPCError{Code: -32601, Message: "Method not found"}
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(res)
+}
+
+func (w *FakeWallet) sendMany(params []json.RawMessage) (interface{}, *template.RPCError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return nil, w.err
+	}
+	if len(params) < 2 {
+		return nil, &template.RPCError{Code: -1, Message: "missing amounts"}
+	}
+
+	amounts := make(map[string]json.Number)
+	if err := json.Unmarshal(params[1], &amounts); err != nil {
+		return nil, &template.RPCError{Code: -3, Message: err.Error()}
+	}
+	w.payments = append(w.payments, amounts)
+
+	return TxID(len(w.payments)), nil
+}
@@ -0,0 +1,136 @@
+package payout
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"stratum-server/config"
+	"stratum-server/repository"
+	"time"
+)
+
+const (
+	// result of the accepted shares, as persisted by the share writer
+	acceptedShare = "accepted"
+)
+
+type pplns struct {
+	*ledger
+	repository  repository.Repository
+	sharesTable config.PostgreSQLTableConfig
+	window      float64
+	fee         float64
+}
+
+// NewPPLNS creates a pay per last N shares scheme, where N is the configured window in difficulty units.
+func NewPPLNS(repository repository.Repository, cfg *config.Config) Scheme {
+	return &pplns{
+		ledger:      newLedger(cfg, config.PayoutSchemePPLNS),
+		repository:  repository,
+		sharesTable: cfg.SharesTable,
+		window:      cfg.PPLNSWindow,
+		fee:         cfg.PoolFee,
+	}
+}
+
+// ShareCredit: shares are only paid when a block is found
+func (p *pplns) ShareCredit(sh Share, r Round) float64 {
+	return 0
+}
+
+// BlockFound: splits the block reward between the accounts of the last N shares
+func (p *pplns) BlockFound(b Block) error {
+	shares, err := p.getShares(b.FoundAt)
+	if err != nil {
+		return err
+	}
+
+	credits := CalculatePPLNS(shares, p.window, b.Reward, p.fee)
+	if len(credits) == 0 {
+		log.Printf("[payout] no shares to credit for block %s", b.Hash)
+		return nil
+	}
+	log.Printf("[payout] crediting %d accounts for block %s", len(credits), b.Hash)
+
+	for i := range credits {
+		credits[i].Height = b.Height
+		credits[i].BlockHash = b.Hash
+	}
+	return p.CreateCredits(p.repository, credits)
+}
+
+// CalculatePPLNS: walks back the shares, sorted from newest to oldest, until the window is filled and splits
+// the reward minus the pool fee proportionally to the difficulty of each account. The share crossing the
+// window boundary is only partially counted. Amounts are rounded down, the remainder is kept by the pool.
+func CalculatePPLNS(shares []Share, window float64, reward int64, fee float64) []Credit {
+	weights := make(map[string]float64)
+	total := 0.0
+	for _, sh := range shares {
+		weight := sh.Difficulty
+		if total+weight > window {
+			weight = window - total
+		}
+		if weight <= 0 {
+			break
+		}
+		weights[sh.Account] += weight
+		total += weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	accounts := make([]string, 0, len(weights))
+	for account := range weights {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+
+	distributable := float64(reward) * (1 - fee)
+	credits := make([]Credit, 0, len(accounts))
+	for _, account := range accounts {
+		amount := math.Floor(distributable * weights[account] / total)
+		if amount <= 0 {
+			continue
+		}
+		credits = append(credits, Credit{Account: account, Amount: amount})
+	}
+
+	return credits
+}
+
+// getShares: returns the accepted shares filling the window before the given time, from newest to oldest
+func (p *pplns) getShares(before time.Time) ([]Share, error) {
+	sqlStatement := fmt.Sprintf(`
+	SELECT account, difficulty
+	FROM (
+		SELECT account, difficulty, SUM(difficulty) OVER (ORDER BY created_at DESC, id DESC) AS total
+		FROM %s.%s
+		WHERE result = $1 AND created_at <= $2
+	) AS window_shares
+	WHERE total - difficulty < $3
+	ORDER BY total`, p.sharesTable.Schema, p.sharesTable.Name)
+
+	var shares []Share
+	if err := p.repository.QueryRows(repository.QueryRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			acceptedShare,
+			before,
+			p.window,
+		},
+	}, func(scan repository.RowScanner) error {
+		sh := Share{}
+		if err := scan(&sh.Account, &sh.Difficulty); err != nil {
+			return err
+		}
+		shares = append(shares, sh)
+		return nil
+	}); err != nil {
+		log.Printf("error getting shares: %v", err)
+		return nil, err
+	}
+
+	return shares, nil
+}
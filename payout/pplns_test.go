@@ -0,0 +1,194 @@
+package payout
+
+import (
+	"fmt"
+	"stratum-server/config"
+	"stratum-server/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculatePPLNS(t *testing.T) {
+	tests := []struct {
+		name            string
+		shares          []Share
+		window          float64
+		reward          int64
+		fee             float64
+		expectedCredits []Credit
+	}{
+		{
+			name:   "no shares",
+			window: 100,
+			reward: 625000000,
+		},
+		{
+			name: "single account",
+			shares: []Share{
+				{Account: "alice", Difficulty: 10},
+				{Account: "alice", Difficulty: 20},
+			},
+			window: 100,
+			reward: 625000000,
+			expectedCredits: []Credit{
+				{Account: "alice", Amount: 625000000},
+			},
+		},
+		{
+			name: "weighted by difficulty",
+			shares: []Share{
+				{Account: "bob", Difficulty: 30},
+				{Account: "alice", Difficulty: 10},
+				{Account: "bob", Difficulty: 50},
+				{Account: "carol", Difficulty: 10},
+			},
+			window: 100,
+			reward: 1000,
+			expectedCredits: []Credit{
+				{Account: "alice", Amount: 100},
+				{Account: "bob", Amount: 800},
+				{Account: "carol", Amount: 100},
+			},
+		},
+		{
+			name: "shares out of the window are ignored",
+			shares: []Share{
+				{Account: "alice", Difficulty: 50},
+				{Account: "bob", Difficulty: 50},
+				{Account: "carol", Difficulty: 1000},
+			},
+			window: 100,
+			reward: 1000,
+			expectedCredits: []Credit{
+				{Account: "alice", Amount: 500},
+				{Account: "bob", Amount: 500},
+			},
+		},
+		{
+			name: "share crossing the window is partially counted",
+			shares: []Share{
+				{Account: "alice", Difficulty: 75},
+				{Account: "bob", Difficulty: 100},
+			},
+			window: 100,
+			reward: 1000,
+			expectedCredits: []Credit{
+				{Account: "alice", Amount: 750},
+				{Account: "bob", Amount: 250},
+			},
+		},
+		{
+			name: "pool fee and rounding",
+			shares: []Share{
+				{Account: "alice", Difficulty: 1},
+				{Account: "bob", Difficulty: 1},
+				{Account: "carol", Difficulty: 1},
+			},
+			window: 100,
+			reward: 1000,
+			fee:    0.01,
+			expectedCredits: []Credit{
+				{Account: "alice", Amount: 330},
+				{Account: "bob", Amount: 330},
+				{Account: "carol", Amount: 330},
+			},
+		},
+		{
+			name: "dust credits are skipped",
+			shares: []Share{
+				{Account: "alice", Difficulty: 999},
+				{Account: "bob", Difficulty: 1},
+			},
+			window: 1000,
+			reward: 100,
+			expectedCredits: []Credit{
+				{Account: "alice", Amount: 99},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credits := CalculatePPLNS(tt.shares, tt.window, tt.reward, tt.fee)
+			assert.Equal(t, tt.expectedCredits, credits)
+		})
+	}
+}
+
+func TestPPLNS_BlockFound(t *testing.T) {
+	foundAt := time.Date(2021, 5, 3, 12, 0, 0, 0, time.UTC)
+	block := Block{Height: 683000, Hash: "00000000000000000003", Reward: 1000, FoundAt: foundAt}
+
+	tests := []struct {
+		name          string
+		shares        []Share
+		queryErr      error
+		insertErr     error
+		expectedRows  [][]interface{}
+		expectedError error
+	}{
+		{
+			name: "credits every account",
+			shares: []Share{
+				{Account: "bob", Difficulty: 40},
+				{Account: "alice", Difficulty: 60},
+			},
+			expectedRows: [][]interface{}{
+				{"alice", int64(683000), "00000000000000000003", config.PayoutSchemePPLNS, float64(594)},
+				{"bob", int64(683000), "00000000000000000003", config.PayoutSchemePPLNS, float64(396)},
+			},
+		},
+		{
+			name: "no shares",
+		},
+		{
+			name:          "error getting shares",
+			queryErr:      fmt.Errorf("connection refused"),
+			expectedError: fmt.Errorf("connection refused"),
+		},
+		{
+			name: "error creating credits",
+			shares: []Share{
+				{Account: "alice", Difficulty: 1},
+			},
+			insertErr:     fmt.Errorf("duplicate key value violates unique constraint"),
+			expectedError: fmt.Errorf("duplicate key value violates unique constraint"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var insertedRows [][]interface{}
+			repo := &RepositoryMock{
+				QueryRowsFunc: func(input repository.QueryRequest, onRow func(scan repository.RowScanner) error) error {
+					assert.Equal(t, []interface{}{acceptedShare, foundAt, float64(100)}, input.Args)
+					if tt.queryErr != nil {
+						return tt.queryErr
+					}
+					for _, sh := range tt.shares {
+						sh := sh
+						if err := onRow(func(destinationArgs ...interface{}) error {
+							*destinationArgs[0].(*string) = sh.Account
+							*destinationArgs[1].(*float64) = sh.Difficulty
+							return nil
+						}); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+				BatchInsertFunc: func(input repository.BatchInsertRequest) error {
+					insertedRows = input.Rows
+					return tt.insertErr
+				},
+			}
+			scheme := NewPPLNS(repo, &config.Config{PayoutConfig: config.PayoutConfig{PPLNSWindow: 100, PoolFee: 0.01}})
+
+			err := scheme.BlockFound(block)
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError == nil {
+				assert.Equal(t, tt.expectedRows, insertedRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package payout
+
+import (
+	"log"
+	"stratum-server/config"
+)
+
+type pps struct {
+	*ledger
+	fee float64
+	// FPPS also pays the expected transaction fees
+	includeFees bool
+}
+
+// NewPPS creates a pay per share scheme, crediting the expected block subsidy of every accepted share.
+func NewPPS(cfg *config.Config) Scheme {
+	return &pps{
+		ledger: newLedger(cfg, config.PayoutSchemePPS),
+		fee:    cfg.PoolFee,
+	}
+}
+
+// NewFPPS creates a full pay per share scheme, crediting the expected block subsidy and transaction fees
+// of every accepted share.
+func NewFPPS(cfg *config.Config) Scheme {
+	return &pps{
+		ledger:      newLedger(cfg, config.PayoutSchemeFPPS),
+		fee:         cfg.PoolFee,
+		includeFees: true,
+	}
+}
+
+// ShareCredit: share difficulty / network difficulty × block reward × (1 − fee)
+func (p *pps) ShareCredit(sh Share, r Round) float64 {
+	if r.NetworkDifficulty <= 0 {
+		return 0
+	}
+
+	reward := float64(r.Subsidy)
+	if p.includeFees {
+		reward += float64(r.Fees)
+	}
+	return sh.Difficulty / r.NetworkDifficulty * reward * (1 - p.fee)
+}
+
+// BlockFound: the shares are already paid, so the whole reward is kept by the pool
+func (p *pps) BlockFound(b Block) error {
+	log.Printf("[payout] block %s reward kept by the pool", b.Hash)
+	return nil
+}
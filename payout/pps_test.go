@@ -0,0 +1,84 @@
+package payout
+
+import (
+	"stratum-server/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPPS_ShareCredit(t *testing.T) {
+	round := Round{
+		Height:            683000,
+		NetworkDifficulty: 1000000,
+		Subsidy:           625000000,
+		Fees:              25000000,
+	}
+
+	tests := []struct {
+		name           string
+		scheme         string
+		fee            float64
+		share          Share
+		round          Round
+		expectedCredit float64
+	}{
+		{
+			name:           "pps",
+			scheme:         config.PayoutSchemePPS,
+			share:          Share{Account: "alice", Difficulty: 1000},
+			round:          round,
+			expectedCredit: 625000,
+		},
+		{
+			name:           "pps with pool fee",
+			scheme:         config.PayoutSchemePPS,
+			fee:            0.02,
+			share:          Share{Account: "alice", Difficulty: 1000},
+			round:          round,
+			expectedCredit: 612500,
+		},
+		{
+			name:           "fpps includes the template fees",
+			scheme:         config.PayoutSchemeFPPS,
+			share:          Share{Account: "alice", Difficulty: 1000},
+			round:          round,
+			expectedCredit: 650000,
+		},
+		{
+			name:           "fpps with pool fee",
+			scheme:         config.PayoutSchemeFPPS,
+			fee:            0.02,
+			share:          Share{Account: "alice", Difficulty: 1000},
+			round:          round,
+			expectedCredit: 637000,
+		},
+		{
+			name:           "fractional credits",
+			scheme:         config.PayoutSchemePPS,
+			share:          Share{Account: "alice", Difficulty: 1},
+			round:          Round{NetworkDifficulty: 20000000000000, Subsidy: 625000000},
+			expectedCredit: 0.00003125,
+		},
+		{
+			name:   "unknown network difficulty",
+			scheme: config.PayoutSchemePPS,
+			share:  Share{Account: "alice", Difficulty: 1000},
+			round:  Round{Subsidy: 625000000},
+		},
+		{
+			name:   "pplns only pays on found blocks",
+			scheme: config.PayoutSchemePPLNS,
+			share:  Share{Account: "alice", Difficulty: 1000},
+			round:  round,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := NewScheme(nil, &config.Config{PayoutConfig: config.PayoutConfig{Scheme: tt.scheme, PoolFee: tt.fee}})
+
+			credit := scheme.ShareCredit(tt.share, tt.round)
+			assert.InDelta(t, tt.expectedCredit, credit, 1e-9)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package payout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"stratum-server/template"
+)
+
+const (
+	satoshisPerBitcoin = 100000000
+	// confirmations required for the wallet inputs
+	sendManyMinConf = 1
+)
+
+// Wallet sends the payments of the pool.
+type Wallet interface {
+	// SendMany: pays the amounts, in satoshis by address, in a single transaction returning its txid
+	SendMany(ctx context.Context, amounts map[string]int64, comment string) (string, error)
+}
+
+type rpcWallet struct {
+	client *template.Client
+}
+
+// NewRPCWallet creates a wallet backed by the wallet RPC of a bitcoind-compatible node.
+func NewRPCWallet(client *template.Client) Wallet {
+	return &rpcWallet{
+		client: client,
+	}
+}
+
+func (w *rpcWallet) SendMany(ctx context.Context, amounts map[string]int64, comment string) (string, error) {
+	// amounts are sent in bitcoins, formatted without floating point errors
+	btcAmounts := make(map[string]json.Number, len(amounts))
+	for address, amount := range amounts {
+		btcAmounts[address] = json.Number(fmt.Sprintf("%d.%08d", amount/satoshisPerBitcoin, amount%satoshisPerBitcoin))
+	}
+
+	var txid string
+	if err := w.client.Call(ctx, "sendmany", []interface{}{"", btcAmounts, sendManyMinConf, comment}, &txid); err != nil {
+		return "", err
+	}
+	return txid, nil
+}
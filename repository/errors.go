@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+const (
+	uniqueViolationCode = "23505"
+)
+
+// IsUniqueViolation: whether the statement failed because of a unique constraint
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "unique violation",
+			err:      &pq.Error{Code: "23505"},
+			expected: true,
+		},
+		{
+			name:     "wrapped unique violation",
+			err:      fmt.Errorf("error updating: %w", &pq.Error{Code: "23505"}),
+			expected: true,
+		},
+		{
+			name: "other postgres error",
+			err:  &pq.Error{Code: "23503"},
+		},
+		{
+			name: "no rows",
+			err:  sql.ErrNoRows,
+		},
+		{
+			name: "no error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsUniqueViolation(tt.err))
+		})
+	}
+}
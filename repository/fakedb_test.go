@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+)
+
+const fakeDriver = "fakedb"
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = make(map[string]*fakeDB)
+)
+
+func init() {
+	sql.Register(fakeDriver, fakeDBDriver{})
+}
+
+// fakeDB records the statements and transaction events run against it, failing the nth exec when failExec is set.
+type fakeDB struct {
+	mu       sync.Mutex
+	events   []string
+	execs    int
+	failExec int
+}
+
+// openFakeDB: registers the fake DB under the given name and opens it
+func openFakeDB(name string, db *fakeDB) (*sql.DB, error) {
+	fakeDBsMu.Lock()
+	fakeDBs[name] = db
+	fakeDBsMu.Unlock()
+	return sql.Open(fakeDriver, name)
+}
+
+func (db *fakeDB) record(event string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.events = append(db.events, event)
+}
+
+func (db *fakeDB) recorded() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]string(nil), db.events...)
+}
+
+type fakeDBDriver struct{}
+
+func (fakeDBDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	db, ok := fakeDBs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown fake db %s", name)
+	}
+	return &fakeConn{db: db}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.record("begin")
+	return &fakeTx{db: c.db}, nil
+}
+
+type fakeStmt struct {
+	db *fakeDB
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	s.db.execs++
+	failed := s.db.execs == s.db.failExec
+	s.db.mu.Unlock()
+
+	if failed {
+		s.db.record("exec failed")
+		return nil, fmt.Errorf("exec %d failed", s.db.failExec)
+	}
+	s.db.record(fmt.Sprintf("exec %d args", len(args)))
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, fmt.Errorf("queries aren't supported by the fake db")
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.db.record("commit")
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.record("rollback")
+	return nil
+}
@@ -1,16 +1,22 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
 	"stratum-server/config"
+	"stratum-server/metrics"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
 const (
 	postgresDriver = "postgres"
+	// max amount of params allowed by postgres in a single statement
+	maxParams = 65535
 )
 
 type request struct {
@@ -33,19 +39,46 @@ type UpdateRequest struct {
 	Args  []interface{}
 }
 
+type BatchInsertRequest struct {
+	// INSERT statement without the VALUES clause
+	Query string
+	Rows  [][]interface{}
+}
+
+// RowScanner copies the columns of the current row into the destination args.
+type RowScanner func(destinationArgs ...interface{}) error
+
 // Repository describes interface to deal with repository.
 type Repository interface {
 	Query(input QueryRequest, destinationArgs ...interface{}) error
+	// QueryRows: calls onRow for every row returned by the query, stopping at the first error
+	QueryRows(input QueryRequest, onRow func(scan RowScanner) error) error
 	Insert(input InsertRequest, destinationArgs ...interface{}) error
 	Update(input UpdateRequest, destinationArgs ...interface{}) error
+	BatchInsert(input BatchInsertRequest) error
+	// Transaction: runs fn with a repository bound to a single transaction, committed when fn returns nil and rolled
+	// back otherwise
+	Transaction(fn func(tx Repository) error) error
+	// Ping: checks that the DB is reachable
+	Ping(ctx context.Context) error
 }
 
 type postgres struct {
 	db *sql.DB
+	// set when the repository is bound to a transaction
+	tx      *sql.Tx
+	metrics metrics.Metrics
+}
+
+// querier is implemented by both the DB and its transactions.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
 // NewService creates new instance for devices service.
-func NewRepository(cfg config.PostgreSQLConfig) *postgres {
+func NewRepository(cfg config.PostgreSQLConfig, m metrics.Metrics) *postgres {
 	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		cfg.Host,
 		cfg.Port,
@@ -64,11 +97,19 @@ func NewRepository(cfg config.PostgreSQLConfig) *postgres {
 	}
 
 	return &postgres{
-		db: db,
+		db:      db,
+		metrics: m,
 	}
 }
 
+// observe: records the duration of the operation started at the given time
+func (psql *postgres) observe(operation string, start time.Time) {
+	psql.metrics.DBQueryObserved(operation, time.Since(start))
+}
+
 func (psql *postgres) Query(input QueryRequest, destinationArgs ...interface{}) error {
+	defer psql.observe("query", time.Now())
+
 	req := request {
 		query: input.Query,
 		args: input.Args,
@@ -81,7 +122,33 @@ func (psql *postgres) Query(input QueryRequest, destinationArgs ...interface{})
 	return nil
 }
 
+func (psql *postgres) QueryRows(input QueryRequest, onRow func(scan RowScanner) error) error {
+	defer psql.observe("query_rows", time.Now())
+
+	rows, err := psql.querier().Query(input.Query, input.Args...)
+	if err != nil {
+		log.Printf("error performing QueryRows: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := onRow(rows.Scan); err != nil {
+			log.Printf("error scanning row: %v", err)
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("error iterating rows: %v", err)
+		return err
+	}
+
+	return nil
+}
+
 func (psql *postgres) Insert(input InsertRequest, destinationArgs ...interface{}) error {
+	defer psql.observe("insert", time.Now())
+
 	req := request {
 		query: input.Query,
 		args: input.Args,
@@ -95,6 +162,8 @@ func (psql *postgres) Insert(input InsertRequest, destinationArgs ...interface{}
 }
 
 func (psql *postgres) Update(input UpdateRequest, destinationArgs ...interface{}) error {
+	defer psql.observe("update", time.Now())
+
 	req := request {
 		query: input.Query,
 		args: input.Args,
@@ -107,8 +176,128 @@ func (psql *postgres) Update(input UpdateRequest, destinationArgs ...interface{}
 	return nil
 }
 
+func (psql *postgres) BatchInsert(input BatchInsertRequest) error {
+	defer psql.observe("batch_insert", time.Now())
+
+	if len(input.Rows) == 0 {
+		return nil
+	}
+
+	batches := splitBatch(input.Rows, maxParams)
+	if len(batches) == 1 {
+		if err := psql.exec(buildBatchInsert(input.Query, batches[0])); err != nil {
+			log.Printf("error performing BatchInsert: %v", err)
+			return err
+		}
+		return nil
+	}
+
+	// the statements are run in a transaction, so that the rows are either all stored or none
+	return psql.inTransaction(func(tx *postgres) error {
+		for _, rows := range batches {
+			if err := tx.exec(buildBatchInsert(input.Query, rows)); err != nil {
+				log.Printf("error performing BatchInsert: %v", err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (psql *postgres) Transaction(fn func(tx Repository) error) error {
+	defer psql.observe("transaction", time.Now())
+
+	return psql.inTransaction(func(tx *postgres) error {
+		return fn(tx)
+	})
+}
+
+func (psql *postgres) Ping(ctx context.Context) error {
+	defer psql.observe("ping", time.Now())
+
+	if err := psql.db.PingContext(ctx); err != nil {
+		log.Printf("error performing Ping: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// splitBatch: splits the rows so that every statement fits the params limit
+func splitBatch(rows [][]interface{}, maxParams int) [][][]interface{} {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	batchSize := maxParams / len(rows[0])
+	batches := make([][][]interface{}, 0, (len(rows)+batchSize-1)/batchSize)
+	for start := 0; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batches = append(batches, rows[start:end])
+	}
+	return batches
+}
+
+func buildBatchInsert(query string, rows [][]interface{}) request {
+	req := request{}
+	values := make([]string, 0, len(rows))
+	for _, row := range rows {
+		placeholders := make([]string, 0, len(row))
+		for _, arg := range row {
+			req.args = append(req.args, arg)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(req.args)))
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+	}
+	req.query = query + " VALUES " + strings.Join(values, ", ")
+	return req
+}
+
+// inTransaction: runs fn bound to a transaction, committed when fn succeeds and rolled back otherwise. When the
+// repository is already bound to a transaction, fn joins it.
+func (psql *postgres) inTransaction(fn func(tx *postgres) error) error {
+	if psql.tx != nil {
+		return fn(psql)
+	}
+
+	tx, err := psql.db.Begin()
+	if err != nil {
+		log.Printf("error beginning transaction: %v", err)
+		return err
+	}
+	if err := fn(&postgres{db: psql.db, tx: tx, metrics: psql.metrics}); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Printf("error rolling back transaction: %v", rollbackErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing transaction: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (psql *postgres) querier() querier {
+	if psql.tx != nil {
+		return psql.tx
+	}
+	return psql.db
+}
+
+func (psql *postgres) exec(req request) error {
+	if _, err := psql.querier().Exec(req.query, req.args...); err != nil {
+		log.Printf("error performing exec: %v", err)
+		return err
+	}
+	return nil
+}
+
 func (psql *postgres) queryRow(req request, destinationArgs ...interface{}) error {
-	if err := psql.db.QueryRow(req.query, req.args...).Scan(destinationArgs...); err != nil {
+	if err := psql.querier().QueryRow(req.query, req.args...).Scan(destinationArgs...); err != nil {
 		log.Printf("error performing queryRow: %v", err)
 		return err
 	}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"fmt"
+	"stratum-server/metrics"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildBatchInsert(t *testing.T) {
+	tests := []struct {
+		name          string
+		rows          [][]interface{}
+		expectedQuery string
+		expectedArgs  []interface{}
+	}{
+		{
+			name:          "single row",
+			rows:          [][]interface{}{{"alice", 1}},
+			expectedQuery: "INSERT INTO public.shares (account, difficulty) VALUES ($1, $2)",
+			expectedArgs:  []interface{}{"alice", 1},
+		},
+		{
+			name:          "placeholders are numbered across rows",
+			rows:          [][]interface{}{{"alice", 1}, {"bob", 2}, {"carol", nil}},
+			expectedQuery: "INSERT INTO public.shares (account, difficulty) VALUES ($1, $2), ($3, $4), ($5, $6)",
+			expectedArgs:  []interface{}{"alice", 1, "bob", 2, "carol", nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := buildBatchInsert("INSERT INTO public.shares (account, difficulty)", tt.rows)
+			assert.Equal(t, tt.expectedQuery, req.query)
+			assert.Equal(t, tt.expectedArgs, req.args)
+		})
+	}
+}
+
+func TestSplitBatch(t *testing.T) {
+	rows := func(n, columns int) [][]interface{} {
+		r := make([][]interface{}, n)
+		for i := range r {
+			r[i] = make([]interface{}, columns)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name          string
+		rows          [][]interface{}
+		maxParams     int
+		expectedSizes []int
+	}{
+		{
+			name:      "no rows",
+			maxParams: maxParams,
+		},
+		{
+			name:          "rows fitting a single statement",
+			rows:          rows(500, 8),
+			maxParams:     maxParams,
+			expectedSizes: []int{500},
+		},
+		{
+			name:          "rows exactly filling the params limit",
+			rows:          rows(8191, 8),
+			maxParams:     maxParams,
+			expectedSizes: []int{8191},
+		},
+		{
+			name:          "rows over the params limit",
+			rows:          rows(8192, 8),
+			maxParams:     maxParams,
+			expectedSizes: []int{8191, 1},
+		},
+		{
+			name:          "several statements",
+			rows:          rows(7, 3),
+			maxParams:     6,
+			expectedSizes: []int{2, 2, 2, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sizes []int
+			for _, batch := range splitBatch(tt.rows, tt.maxParams) {
+				assert.LessOrEqual(t, len(batch)*len(batch[0]), tt.maxParams)
+				sizes = append(sizes, len(batch))
+			}
+			assert.Equal(t, tt.expectedSizes, sizes)
+		})
+	}
+}
+
+func TestPostgres_BatchInsert(t *testing.T) {
+	const columns = 5
+	rows := func(n int) [][]interface{} {
+		r := make([][]interface{}, n)
+		for i := range r {
+			r[i] = []interface{}{"alice", int64(700000), "hash", "pplns", 1.5}
+		}
+		return r
+	}
+	// rows filling a single statement
+	batchSize := maxParams / columns
+
+	tests := []struct {
+		name           string
+		rows           [][]interface{}
+		failExec       int
+		expectedEvents []string
+		expectedError  bool
+	}{
+		{
+			name: "no rows",
+		},
+		{
+			name:           "single statement",
+			rows:           rows(2),
+			expectedEvents: []string{"exec 10 args"},
+		},
+		{
+			name:           "statements run in a transaction",
+			rows:           rows(batchSize + 1),
+			expectedEvents: []string{"begin", fmt.Sprintf("exec %d args", batchSize*columns), "exec 5 args", "commit"},
+		},
+		{
+			name:           "credits are not half applied when a statement fails",
+			rows:           rows(batchSize + 1),
+			failExec:       2,
+			expectedEvents: []string{"begin", fmt.Sprintf("exec %d args", batchSize*columns), "exec failed", "rollback"},
+			expectedError:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeDB{failExec: tt.failExec}
+			db, err := openFakeDB(t.Name(), fake)
+			assert.NoError(t, err)
+			defer db.Close()
+			psql := &postgres{db: db, metrics: metrics.NewNoop()}
+
+			err = psql.BatchInsert(BatchInsertRequest{
+				Query: "INSERT INTO public.credits (account, block_height, block_hash, scheme, amount)",
+				Rows:  tt.rows,
+			})
+			assert.Equal(t, tt.expectedError, err != nil)
+			assert.Equal(t, tt.expectedEvents, fake.recorded())
+		})
+	}
+}
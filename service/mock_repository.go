@@ -0,0 +1,360 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package service
+
+import (
+	"context"
+	"stratum-server/repository"
+	"sync"
+)
+
+var (
+	lockRepositoryMockBatchInsert sync.RWMutex
+	lockRepositoryMockInsert      sync.RWMutex
+	lockRepositoryMockPing        sync.RWMutex
+	lockRepositoryMockQuery       sync.RWMutex
+	lockRepositoryMockQueryRows   sync.RWMutex
+	lockRepositoryMockTransaction sync.RWMutex
+	lockRepositoryMockUpdate      sync.RWMutex
+)
+
+// Ensure, that RepositoryMock does implement repository.Repository.
+// If this is not the case, regenerate this file with moq.
+var _ repository.Repository = &RepositoryMock{}
+
+// RepositoryMock is a mock implementation of repository.Repository.
+//
+//     func TestSomethingThatUsesRepository(t *testing.T) {
+//
+//         // make and configure a mocked repository.Repository
+//         mockedRepository := &RepositoryMock{
+//             BatchInsertFunc: func(input repository.BatchInsertRequest) error {
+// 	               panic("mock out the BatchInsert method")
+//             },
+//             InsertFunc: func(input repository.InsertRequest, destinationArgs ...interface{}) error {
+// 	               panic("mock out the Insert method")
+//             },
+//             PingFunc: func(ctx context.Context) error {
+// 	               panic("mock out the Ping method")
+//             },
+//             QueryFunc: func(input repository.QueryRequest, destinationArgs ...interface{}) error {
+// 	               panic("mock out the Query method")
+//             },
+//             QueryRowsFunc: func(input repository.QueryRequest, onRow func(scan repository.RowScanner) error) error {
+// 	               panic("mock out the QueryRows method")
+//             },
+//             TransactionFunc: func(fn func(tx repository.Repository) error) error {
+// 	               panic("mock out the Transaction method")
+//             },
+//             UpdateFunc: func(input repository.UpdateRequest, destinationArgs ...interface{}) error {
+// 	               panic("mock out the Update method")
+//             },
+//         }
+//
+//         // use mockedRepository in code that requires repository.Repository
+//         // and then make assertions.
+//
+//     }
+type RepositoryMock struct {
+	// BatchInsertFunc mocks the BatchInsert method.
+	BatchInsertFunc func(input repository.BatchInsertRequest) error
+
+	// InsertFunc mocks the Insert method.
+	InsertFunc func(input repository.InsertRequest, destinationArgs ...interface{}) error
+
+	// PingFunc mocks the Ping method.
+	PingFunc func(ctx context.Context) error
+
+	// QueryFunc mocks the Query method.
+	QueryFunc func(input repository.QueryRequest, destinationArgs ...interface{}) error
+
+	// QueryRowsFunc mocks the QueryRows method.
+	QueryRowsFunc func(input repository.QueryRequest, onRow func(scan repository.RowScanner) error) error
+
+	// TransactionFunc mocks the Transaction method.
+	TransactionFunc func(fn func(tx repository.Repository) error) error
+
+	// UpdateFunc mocks the Update method.
+	UpdateFunc func(input repository.UpdateRequest, destinationArgs ...interface{}) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// BatchInsert holds details about calls to the BatchInsert method.
+		BatchInsert []struct {
+			// Input is the input argument value.
+			Input repository.BatchInsertRequest
+		}
+		// Insert holds details about calls to the Insert method.
+		Insert []struct {
+			// Input is the input argument value.
+			Input repository.InsertRequest
+			// DestinationArgs is the destinationArgs argument value.
+			DestinationArgs []interface{}
+		}
+		// Ping holds details about calls to the Ping method.
+		Ping []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Query holds details about calls to the Query method.
+		Query []struct {
+			// Input is the input argument value.
+			Input repository.QueryRequest
+			// DestinationArgs is the destinationArgs argument value.
+			DestinationArgs []interface{}
+		}
+		// QueryRows holds details about calls to the QueryRows method.
+		QueryRows []struct {
+			// Input is the input argument value.
+			Input repository.QueryRequest
+			// OnRow is the onRow argument value.
+			OnRow func(scan repository.RowScanner) error
+		}
+		// Transaction holds details about calls to the Transaction method.
+		Transaction []struct {
+			// Fn is the fn argument value.
+			Fn func(tx repository.Repository) error
+		}
+		// Update holds details about calls to the Update method.
+		Update []struct {
+			// Input is the input argument value.
+			Input repository.UpdateRequest
+			// DestinationArgs is the destinationArgs argument value.
+			DestinationArgs []interface{}
+		}
+	}
+}
+
+// BatchInsert calls BatchInsertFunc.
+func (mock *RepositoryMock) BatchInsert(input repository.BatchInsertRequest) error {
+	if mock.BatchInsertFunc == nil {
+		panic("RepositoryMock.BatchInsertFunc: method is nil but Repository.BatchInsert was just called")
+	}
+	callInfo := struct {
+		Input repository.BatchInsertRequest
+	}{
+		Input: input,
+	}
+	lockRepositoryMockBatchInsert.Lock()
+	mock.calls.BatchInsert = append(mock.calls.BatchInsert, callInfo)
+	lockRepositoryMockBatchInsert.Unlock()
+	return mock.BatchInsertFunc(input)
+}
+
+// BatchInsertCalls gets all the calls that were made to BatchInsert.
+// Check the length with:
+//     len(mockedRepository.BatchInsertCalls())
+func (mock *RepositoryMock) BatchInsertCalls() []struct {
+	Input repository.BatchInsertRequest
+} {
+	var calls []struct {
+		Input repository.BatchInsertRequest
+	}
+	lockRepositoryMockBatchInsert.RLock()
+	calls = mock.calls.BatchInsert
+	lockRepositoryMockBatchInsert.RUnlock()
+	return calls
+}
+
+// Insert calls InsertFunc.
+func (mock *RepositoryMock) Insert(input repository.InsertRequest, destinationArgs ...interface{}) error {
+	if mock.InsertFunc == nil {
+		panic("RepositoryMock.InsertFunc: method is nil but Repository.Insert was just called")
+	}
+	callInfo := struct {
+		Input           repository.InsertRequest
+		DestinationArgs []interface{}
+	}{
+		Input:           input,
+		DestinationArgs: destinationArgs,
+	}
+	lockRepositoryMockInsert.Lock()
+	mock.calls.Insert = append(mock.calls.Insert, callInfo)
+	lockRepositoryMockInsert.Unlock()
+	return mock.InsertFunc(input, destinationArgs...)
+}
+
+// InsertCalls gets all the calls that were made to Insert.
+// Check the length with:
+//     len(mockedRepository.InsertCalls())
+func (mock *RepositoryMock) InsertCalls() []struct {
+	Input           repository.InsertRequest
+	DestinationArgs []interface{}
+} {
+	var calls []struct {
+		Input           repository.InsertRequest
+		DestinationArgs []interface{}
+	}
+	lockRepositoryMockInsert.RLock()
+	calls = mock.calls.Insert
+	lockRepositoryMockInsert.RUnlock()
+	return calls
+}
+
+// Ping calls PingFunc.
+func (mock *RepositoryMock) Ping(ctx context.Context) error {
+	if mock.PingFunc == nil {
+		panic("RepositoryMock.PingFunc: method is nil but Repository.Ping was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	lockRepositoryMockPing.Lock()
+	mock.calls.Ping = append(mock.calls.Ping, callInfo)
+	lockRepositoryMockPing.Unlock()
+	return mock.PingFunc(ctx)
+}
+
+// PingCalls gets all the calls that were made to Ping.
+// Check the length with:
+//     len(mockedRepository.PingCalls())
+func (mock *RepositoryMock) PingCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	lockRepositoryMockPing.RLock()
+	calls = mock.calls.Ping
+	lockRepositoryMockPing.RUnlock()
+	return calls
+}
+
+// Query calls QueryFunc.
+func (mock *RepositoryMock) Query(input repository.QueryRequest, destinationArgs ...interface{}) error {
+	if mock.QueryFunc == nil {
+		panic("RepositoryMock.QueryFunc: method is nil but Repository.Query was just called")
+	}
+	callInfo := struct {
+		Input           repository.QueryRequest
+		DestinationArgs []interface{}
+	}{
+		Input:           input,
+		DestinationArgs: destinationArgs,
+	}
+	lockRepositoryMockQuery.Lock()
+	mock.calls.Query = append(mock.calls.Query, callInfo)
+	lockRepositoryMockQuery.Unlock()
+	return mock.QueryFunc(input, destinationArgs...)
+}
+
+// QueryCalls gets all the calls that were made to Query.
+// Check the length with:
+//     len(mockedRepository.QueryCalls())
+func (mock *RepositoryMock) QueryCalls() []struct {
+	Input           repository.QueryRequest
+	DestinationArgs []interface{}
+} {
+	var calls []struct {
+		Input           repository.QueryRequest
+		DestinationArgs []interface{}
+	}
+	lockRepositoryMockQuery.RLock()
+	calls = mock.calls.Query
+	lockRepositoryMockQuery.RUnlock()
+	return calls
+}
+
+// QueryRows calls QueryRowsFunc.
+func (mock *RepositoryMock) QueryRows(input repository.QueryRequest, onRow func(scan repository.RowScanner) error) error {
+	if mock.QueryRowsFunc == nil {
+		panic("RepositoryMock.QueryRowsFunc: method is nil but Repository.QueryRows was just called")
+	}
+	callInfo := struct {
+		Input repository.QueryRequest
+		OnRow func(scan repository.RowScanner) error
+	}{
+		Input: input,
+		OnRow: onRow,
+	}
+	lockRepositoryMockQueryRows.Lock()
+	mock.calls.QueryRows = append(mock.calls.QueryRows, callInfo)
+	lockRepositoryMockQueryRows.Unlock()
+	return mock.QueryRowsFunc(input, onRow)
+}
+
+// QueryRowsCalls gets all the calls that were made to QueryRows.
+// Check the length with:
+//     len(mockedRepository.QueryRowsCalls())
+func (mock *RepositoryMock) QueryRowsCalls() []struct {
+	Input repository.QueryRequest
+	OnRow func(scan repository.RowScanner) error
+} {
+	var calls []struct {
+		Input repository.QueryRequest
+		OnRow func(scan repository.RowScanner) error
+	}
+	lockRepositoryMockQueryRows.RLock()
+	calls = mock.calls.QueryRows
+	lockRepositoryMockQueryRows.RUnlock()
+	return calls
+}
+
+// Transaction calls TransactionFunc.
+func (mock *RepositoryMock) Transaction(fn func(tx repository.Repository) error) error {
+	if mock.TransactionFunc == nil {
+		panic("RepositoryMock.TransactionFunc: method is nil but Repository.Transaction was just called")
+	}
+	callInfo := struct {
+		Fn func(tx repository.Repository) error
+	}{
+		Fn: fn,
+	}
+	lockRepositoryMockTransaction.Lock()
+	mock.calls.Transaction = append(mock.calls.Transaction, callInfo)
+	lockRepositoryMockTransaction.Unlock()
+	return mock.TransactionFunc(fn)
+}
+
+// TransactionCalls gets all the calls that were made to Transaction.
+// Check the length with:
+//     len(mockedRepository.TransactionCalls())
+func (mock *RepositoryMock) TransactionCalls() []struct {
+	Fn func(tx repository.Repository) error
+} {
+	var calls []struct {
+		Fn func(tx repository.Repository) error
+	}
+	lockRepositoryMockTransaction.RLock()
+	calls = mock.calls.Transaction
+	lockRepositoryMockTransaction.RUnlock()
+	return calls
+}
+
+// Update calls UpdateFunc.
+func (mock *RepositoryMock) Update(input repository.UpdateRequest, destinationArgs ...interface{}) error {
+	if mock.UpdateFunc == nil {
+		panic("RepositoryMock.UpdateFunc: method is nil but Repository.Update was just called")
+	}
+	callInfo := struct {
+		Input           repository.UpdateRequest
+		DestinationArgs []interface{}
+	}{
+		Input:           input,
+		DestinationArgs: destinationArgs,
+	}
+	lockRepositoryMockUpdate.Lock()
+	mock.calls.Update = append(mock.calls.Update, callInfo)
+	lockRepositoryMockUpdate.Unlock()
+	return mock.UpdateFunc(input, destinationArgs...)
+}
+
+// UpdateCalls gets all the calls that were made to Update.
+// Check the length with:
+//     len(mockedRepository.UpdateCalls())
+func (mock *RepositoryMock) UpdateCalls() []struct {
+	Input           repository.UpdateRequest
+	DestinationArgs []interface{}
+} {
+	var calls []struct {
+		Input           repository.UpdateRequest
+		DestinationArgs []interface{}
+	}
+	lockRepositoryMockUpdate.RLock()
+	calls = mock.calls.Update
+	lockRepositoryMockUpdate.RUnlock()
+	return calls
+}
@@ -2,8 +2,15 @@ package service
 
 import (
 	"context"
+	"encoding/hex"
+	"net"
 	"stratum-server/config"
+	"stratum-server/metrics"
+	"stratum-server/payout"
 	"stratum-server/repository"
+	"stratum-server/template"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -18,8 +25,24 @@ type AppError struct {
 type Service interface {
 	// Health: returns server status
 	Health() *HealthResponse
+	// Ready: checks every dependency needed to serve miners
+	Ready(ctx context.Context) *ReadinessResponse
 	// RunWebsocketConnection: creates a ws connection
 	RunWebsocketConnection(ctx context.Context, conn *websocket.Conn)
+	// RunTCPConnection: creates a stratum+tcp connection
+	RunTCPConnection(ctx context.Context, conn net.Conn)
+
+	// GetSessions: returns every live subscribed connection
+	GetSessions() []*Session
+	// GetSession: returns the live connection subscribed with the given extraNonce1
+	GetSession(extraNonce1 int64) (*Session, *AppError)
+	// CloseSession: closes the live connection subscribed with the given extraNonce1, marking its subscription as inactive
+	CloseSession(extraNonce1 int64) *AppError
+	// SetExtraNonce: moves the live connection subscribed with the given extraNonce1 to a new extraNonce1 and
+	// extraNonce2 size, notifying the miner with mining.set_extranonce
+	SetExtraNonce(extraNonce1, newExtraNonce1, extraNonce2 int64) (*Session, *AppError)
+	// Drain: asks every live connection to reconnect and marks every subscription as inactive, refusing new connections
+	Drain(ctx context.Context) error
 
 	// GenerateExtraNonce2: creates a valid ExtraNonce2. Right now it returns 4
 	GetExtraNonce2() int64
@@ -28,12 +51,71 @@ type Service interface {
 type service struct {
 	repository         repository.Repository
 	subscriptionsTable config.PostgreSQLTableConfig
+	blocksTable        config.PostgreSQLTableConfig
+	sharesTable        config.PostgreSQLTableConfig
+	accountsTable      config.PostgreSQLTableConfig
+	instancesTable     config.PostgreSQLTableConfig
+	authMode           string
+	network            string
+	protocolDialect    string
+	vardiffConfig      config.VardiffConfig
+	poolConfig         config.PoolConfig
+	payoutScript       []byte
+	templateSource     template.Source
+	templateMaxAge     time.Duration
+	payoutScheme       payout.Scheme
+	jobs               *jobManager
+	hub                *Hub
+	metrics            metrics.Metrics
+	hashrate           *hashrateEstimator
+	shareQueue         chan *shareRecord
+	// credited shares that didn't fit in the share queue, kept until the writer persists them
+	overflowMu     sync.Mutex
+	creditOverflow []*shareRecord
+	instanceConfig config.InstanceConfig
+	shutdownConfig config.ShutdownConfig
+	// set to 1 once draining, accessed atomically
+	draining int32
 }
 
-// NewService creates new instance for devices service.
-func NewService(repository repository.Repository, subscriptionsTable config.PostgreSQLTableConfig) *service {
+// NewService creates new instance for devices service. The template source is nil when there's no node configured,
+// and the metrics are discarded when nil.
+func NewService(repository repository.Repository, cfg *config.Config, templateSource template.Source, m metrics.Metrics) *service {
+	if m == nil {
+		m = metrics.NewNoop()
+	}
+
+	// already validated when loading the config
+	payoutScript, _ := hex.DecodeString(cfg.PayoutScript)
+
+	// in solo mode the reward is paid directly to the finder
+	var payoutScheme payout.Scheme
+	if cfg.Mode == config.MiningModePool {
+		payoutScheme = payout.NewScheme(repository, cfg)
+	}
+
 	return &service{
 		repository:         repository,
-		subscriptionsTable: subscriptionsTable,
+		subscriptionsTable: cfg.SubscriptionsTable,
+		blocksTable:        cfg.BlocksTable,
+		sharesTable:        cfg.SharesTable,
+		accountsTable:      cfg.AccountsTable,
+		instancesTable:     cfg.InstancesTable,
+		authMode:           cfg.AuthMode,
+		network:            cfg.Network,
+		protocolDialect:    cfg.ProtocolDialect,
+		vardiffConfig:      cfg.VardiffConfig,
+		poolConfig:         cfg.PoolConfig,
+		payoutScript:       payoutScript,
+		templateSource:     templateSource,
+		templateMaxAge:     cfg.TemplateMaxAge,
+		payoutScheme:       payoutScheme,
+		jobs:               newJobManager(),
+		hub:                newHub(),
+		metrics:            m,
+		hashrate:           newHashrateEstimator(hashrateWindow),
+		shareQueue:         make(chan *shareRecord, shareQueueSize),
+		instanceConfig:     cfg.InstanceConfig,
+		shutdownConfig:     cfg.ShutdownConfig,
 	}
 }
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"stratum-server/bitcoin"
+	"stratum-server/config"
+	"stratum-server/repository"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errAuthInvalidCredentials = fmt.Errorf("invalid credentials")
+)
+
+type account struct {
+	name         string
+	passwordHash string
+}
+
+type worker struct {
+	// username as sent in mining.authorize
+	name    string
+	account string
+	// only present when authorizing with payout addresses
+	address *bitcoin.Address
+}
+
+type invalidAddressError struct {
+	err error
+}
+
+func (e *invalidAddressError) Error() string {
+	return fmt.Sprintf("Invalid payout address: %v", e.err)
+}
+
+// parseWorkerName: splits the username in the account and worker parts, following the account.worker format
+func parseWorkerName(username string) (string, string) {
+	parts := strings.SplitN(username, ".", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// authorize: validates the credentials for the given username according to the configured mode
+func (s *service) authorize(username string, password string) (*worker, error) {
+	accountName, _ := parseWorkerName(username)
+	if accountName == "" {
+		return nil, errAuthInvalidCredentials
+	}
+
+	w := &worker{
+		name:    username,
+		account: accountName,
+	}
+
+	switch s.authMode {
+	case config.AuthModePassword:
+		acc, err := s.getAccount(accountName)
+		if err != nil {
+			return nil, err
+		}
+		if acc == nil {
+			return nil, errAuthInvalidCredentials
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(acc.passwordHash), []byte(password)); err != nil {
+			log.Printf("invalid password for account: %s", accountName)
+			return nil, errAuthInvalidCredentials
+		}
+	case config.AuthModeAddress:
+		address, err := bitcoin.DecodeAddress(accountName, s.network)
+		if err != nil {
+			log.Printf("invalid payout address %s: %v", accountName, err)
+			return nil, &invalidAddressError{err: err}
+		}
+		w.address = address
+	}
+
+	return w, nil
+}
+
+func (s *service) getAccount(name string) (*account, error) {
+	sqlStatement := fmt.Sprintf(`
+	SELECT name, password_hash
+	FROM %s.%s
+	WHERE name = $1`, s.accountsTable.Schema, s.accountsTable.Name)
+
+	acc := &account{}
+	if err := s.repository.Query(repository.QueryRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			name,
+		},
+	}, &acc.name, &acc.passwordHash); err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("no account found for name: %s", name)
+			return nil, nil
+		}
+		log.Printf("error getting account: %v", err)
+		return nil, err
+	}
+
+	return acc, nil
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"stratum-server/config"
+	"stratum-server/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newAccountsRepository: serves the account "user" with the password "pass", or fails every query with queryErr
+func newAccountsRepository(t *testing.T, queryErr error) *RepositoryMock {
+	hash, err := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	return &RepositoryMock{
+		QueryFunc: func(input repository.QueryRequest, destinationArgs ...interface{}) error {
+			if queryErr != nil {
+				return queryErr
+			}
+			if input.Args[0] != "user" {
+				return sql.ErrNoRows
+			}
+			*destinationArgs[0].(*string) = "user"
+			*destinationArgs[1].(*string) = string(hash)
+			return nil
+		},
+	}
+}
+
+func TestService_authorize(t *testing.T) {
+	tests := []struct {
+		name            string
+		authMode        string
+		username        string
+		password        string
+		queryErr        error
+		expectedAccount string
+		expectedAddress bool
+		expectedError   string
+	}{
+		{
+			name:            "none mode",
+			authMode:        config.AuthModeNone,
+			username:        "user.rig1",
+			password:        "pass",
+			expectedAccount: "user",
+		},
+		{
+			name:          "error with empty account",
+			authMode:      config.AuthModeNone,
+			username:      ".rig1",
+			expectedError: errAuthInvalidCredentials.Error(),
+		},
+		{
+			name:            "address mode",
+			authMode:        config.AuthModeAddress,
+			username:        "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4.rig1",
+			expectedAccount: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			expectedAddress: true,
+		},
+		{
+			name:            "address mode without worker",
+			authMode:        config.AuthModeAddress,
+			username:        "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+			expectedAccount: "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+			expectedAddress: true,
+		},
+		{
+			name:          "error with address from another network",
+			authMode:      config.AuthModeAddress,
+			username:      "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7.rig1",
+			expectedError: "Invalid payout address: address does not belong to the network",
+		},
+		{
+			name:            "password mode",
+			authMode:        config.AuthModePassword,
+			username:        "user.rig1",
+			password:        "pass",
+			expectedAccount: "user",
+		},
+		{
+			name:          "error with wrong password",
+			authMode:      config.AuthModePassword,
+			username:      "user.rig1",
+			password:      "wrong",
+			expectedError: errAuthInvalidCredentials.Error(),
+		},
+		{
+			name:          "error with unknown account",
+			authMode:      config.AuthModePassword,
+			username:      "other.rig1",
+			password:      "pass",
+			expectedError: errAuthInvalidCredentials.Error(),
+		},
+		{
+			name:          "error getting account",
+			authMode:      config.AuthModePassword,
+			username:      "user.rig1",
+			password:      "pass",
+			queryErr:      fmt.Errorf("connection refused"),
+			expectedError: "connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(newAccountsRepository(t, tt.queryErr), &config.Config{AuthMode: tt.authMode, Network: "mainnet"}, nil, nil)
+
+			w, err := svc.authorize(tt.username, tt.password)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.username, w.name)
+			assert.Equal(t, tt.expectedAccount, w.account)
+			assert.Equal(t, tt.expectedAddress, w.address != nil)
+		})
+	}
+}
+
+func TestWebSocket_handleMiningAuthorize(t *testing.T) {
+	tests := []struct {
+		name          string
+		params        string
+		queryErr      error
+		expectedError *rpcError
+	}{
+		{
+			name:   "authorized worker",
+			params: `["user.rig1","pass"]`,
+		},
+		{
+			name:          "error with wrong password",
+			params:        `["user.rig1","wrong"]`,
+			expectedError: errStratumUnauthorized,
+		},
+		{
+			name:          "error with unknown account",
+			params:        `["other.rig1","pass"]`,
+			expectedError: errStratumUnauthorized,
+		},
+		{
+			name:          "error getting account",
+			params:        `["user.rig1","pass"]`,
+			queryErr:      fmt.Errorf("connection refused"),
+			expectedError: errRPCInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(newAccountsRepository(t, tt.queryErr), &config.Config{AuthMode: config.AuthModePassword}, nil, nil)
+			ws := &webSocket{svc: svc, workers: make(map[string]*worker)}
+
+			res := ws.handleMiningAuthorize(&rpcRequest{ID: []byte("1"), Params: []byte(tt.params)})
+			assert.Equal(t, tt.expectedError, res.Error)
+			_, authorized := ws.workers["user.rig1"]
+			assert.Equal(t, tt.expectedError == nil, authorized)
+		})
+	}
+}
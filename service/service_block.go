@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"stratum-server/bitcoin"
+	"stratum-server/payout"
+	"stratum-server/repository"
+	"stratum-server/template"
+	"time"
+)
+
+const (
+	submitBlockTimeout = 30 * time.Second
+	// result stored when the block couldn't be sent to the node
+	submitBlockFailed = "submission failed"
+)
+
+type block struct {
+	height      int64
+	hash        string
+	extraNonce1 int64
+	subscriber  string
+	worker      string
+	result      string
+	createdAt   time.Time
+}
+
+// submitBlock: assembles the block for a share meeting the network target, sends it to the node and persists the result
+func (s *service) submitBlock(sub *subscription, sh *share, res *shareResult) {
+	header, err := res.header.Serialize()
+	if err != nil {
+		log.Printf("error serializing block header: %v", err)
+		return
+	}
+
+	t := res.job.blockTemplate
+	b := &block{
+		height:      t.Height,
+		hash:        hex.EncodeToString(bitcoin.ReverseBytes(res.hash)),
+		extraNonce1: sub.extraNonce1,
+		subscriber:  sub.subscriber,
+		worker:      sh.worker,
+	}
+	log.Printf("[block] found block %s at height %d by extraNonce1 %08x", b.hash, b.height, b.extraNonce1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), submitBlockTimeout)
+	defer cancel()
+	b.result, err = s.templateSource.SubmitBlock(ctx, t.Block(header, res.coinbase))
+	if err != nil {
+		log.Printf("error submitting block %s: %v", b.hash, err)
+		b.result = submitBlockFailed
+	}
+	log.Printf("[block] block %s submitted with result: %s", b.hash, b.result)
+
+	if err := s.createBlock(b); err != nil {
+		log.Printf("error persisting block %s: %v", b.hash, err)
+		b.createdAt = time.Now()
+	}
+
+	if b.result == template.SubmitBlockAccepted && s.payoutScheme != nil {
+		if err := s.payoutScheme.BlockFound(payout.Block{
+			Height:  b.height,
+			Hash:    b.hash,
+			Reward:  t.CoinbaseValue,
+			FoundAt: b.createdAt,
+		}); err != nil {
+			log.Printf("error crediting block %s: %v", b.hash, err)
+		}
+	}
+}
+
+func (s *service) createBlock(b *block) error {
+	sqlStatement := fmt.Sprintf(`
+	INSERT INTO %s.%s (height, hash, extra_nonce_1, subscriber, worker, result)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING created_at`, s.blocksTable.Schema, s.blocksTable.Name)
+
+	if err := s.repository.Insert(repository.InsertRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			b.height,
+			b.hash,
+			b.extraNonce1,
+			b.subscriber,
+			b.worker,
+			b.result,
+		},
+	}, &b.createdAt); err != nil {
+		log.Printf("error creating block: %v", err)
+		return err
+	}
+
+	return nil
+}
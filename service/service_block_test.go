@@ -0,0 +1,115 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"stratum-server/bitcoin"
+	"stratum-server/config"
+	"stratum-server/repository"
+	"stratum-server/template"
+	"stratum-server/template/templatetest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockTemplateSource records the blocks submitted to the node
+type blockTemplateSource struct {
+	nopTemplateSource
+	blocks chan []byte
+}
+
+func (s *blockTemplateSource) SubmitBlock(_ context.Context, block []byte) (string, error) {
+	s.blocks <- block
+	return template.SubmitBlockAccepted, nil
+}
+
+func TestWebSocket_handleMiningSubmit_block(t *testing.T) {
+	const (
+		extraNonce2 = "00000000"
+		txData      = "0100000001aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa0000000000ffffffff0100000000000000000000000000"
+	)
+
+	res := templatetest.NewGetBlockTemplateResult(101)
+	res.Transactions = []template.TransactionResult{{Data: txData, TxID: fmt.Sprintf("%064x", 1), Fee: 1000}}
+	tmpl, err := template.NewTemplate(res)
+	assert.NoError(t, err)
+
+	source := &blockTemplateSource{blocks: make(chan []byte, 1)}
+	inserts := make(chan []interface{}, 1)
+	repo := &RepositoryMock{
+		InsertFunc: func(input repository.InsertRequest, destinationArgs ...interface{}) error {
+			inserts <- input.Args
+			*destinationArgs[0].(*time.Time) = time.Now()
+			return nil
+		},
+	}
+	svc := NewService(repo, &config.Config{
+		AuthMode: config.AuthModeNone,
+		PoolConfig: config.PoolConfig{
+			Mode:         config.MiningModePool,
+			PayoutScript: "76a914000000000000000000000000000000000000000088ac",
+		},
+		PayoutConfig: config.PayoutConfig{Scheme: config.PayoutSchemePPS},
+		// below the regtest network difficulty, so that every block is also a valid share
+		VardiffConfig: config.VardiffConfig{InitialDifficulty: 1e-10},
+	}, source, nil)
+	job, err := svc.newJob(tmpl)
+	assert.NoError(t, err)
+	job.CleanJobs = true
+	svc.jobs.add(job)
+
+	ws := &webSocket{
+		svc:          svc,
+		inboundMsg:   make(chan []byte, 16),
+		miningConfig: miningConfig{extraNonce2: 4},
+		vardiff:      newVardiff(svc.vardiffConfig),
+		subscription: &subscription{extraNonce1: 1, subscriber: "cgminer/4.10.0"},
+		workers:      map[string]*worker{"alice.rig1": {name: "alice.rig1", account: "alice"}},
+	}
+
+	// the regtest target is met by about half of the hashes
+	var nonce string
+	var header []byte
+	for n := 0; nonce == ""; n++ {
+		sh := &share{worker: "alice.rig1", jobID: job.ID, extraNonce2: extraNonce2, nTime: job.NTime, nonce: fmt.Sprintf("%08x", n)}
+		_, h, err := ws.buildBlockHeader(job, sh, 1, 4)
+		assert.NoError(t, err)
+		hash, err := h.Hash()
+		assert.NoError(t, err)
+		if bitcoin.HashToBig(hash).Cmp(bitcoin.CompactToTarget(h.Bits)) <= 0 {
+			nonce = sh.nonce
+			header, err = h.Serialize()
+			assert.NoError(t, err)
+		}
+	}
+
+	ws.handleMessage([]byte(fmt.Sprintf(`{"id":1,"method":"mining.submit","params":["alice.rig1","%s","%s","%s","%s"]}`,
+		job.ID, extraNonce2, job.NTime, nonce)))
+	assert.Equal(t, `{"id":1,"result":true,"error":null}`, string(<-ws.inboundMsg))
+
+	// header, transaction count, coinbase with the connection extranonce, and the template transactions
+	coinbase, err := hex.DecodeString(job.Coinb1 + "00000001" + extraNonce2 + job.Coinb2)
+	assert.NoError(t, err)
+	tx, err := hex.DecodeString(txData)
+	assert.NoError(t, err)
+	expectedBlock := bytes.Join([][]byte{header, {0x02}, coinbase, tx}, nil)
+
+	select {
+	case block := <-source.blocks:
+		assert.Equal(t, hex.EncodeToString(expectedBlock), hex.EncodeToString(block))
+	case <-time.After(time.Second):
+		t.Fatal("block not submitted")
+	}
+
+	hash := hex.EncodeToString(bitcoin.ReverseBytes(bitcoin.DoubleSHA256(header)))
+	select {
+	case args := <-inserts:
+		assert.Equal(t, []interface{}{int64(101), hash, int64(1), "cgminer/4.10.0", "alice.rig1", template.SubmitBlockAccepted}, args)
+	case <-time.After(time.Second):
+		t.Fatal("block not persisted")
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+)
+
+const (
+	drainMessage = "server shutting down, reconnecting"
+)
+
+// Drain: asks every live session to reconnect, waits for the miners to leave until the drain timeout and marks every
+// subscription as inactive. New connections are refused from then on.
+func (s *service) Drain(ctx context.Context) error {
+	atomic.StoreInt32(&s.draining, 1)
+
+	sessions := s.hub.list()
+	log.Printf("draining %d sessions", len(sessions))
+	for _, ws := range sessions {
+		ws.sendNotification(newShowMessageNotification(drainMessage))
+		ws.sendNotification(newReconnectNotification(s.shutdownConfig.ReconnectHost, s.shutdownConfig.ReconnectPort))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.shutdownConfig.DrainTimeout)
+	defer cancel()
+	for _, ws := range sessions {
+		select {
+		case <-ws.done:
+		case <-ctx.Done():
+		}
+	}
+
+	// miners that didn't reconnect in time, or subscribed meanwhile, are dropped
+	remaining := s.hub.list()
+	if len(remaining) > 0 {
+		log.Printf("closing %d sessions still connected after draining", len(remaining))
+	}
+	for _, ws := range remaining {
+		ws.CloseConn()
+	}
+
+	return s.inactiveSubscriptions()
+}
+
+func (s *service) isDraining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
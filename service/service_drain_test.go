@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"stratum-server/config"
+	"stratum-server/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_Drain(t *testing.T) {
+	tests := []struct {
+		name          string
+		shutdown      config.ShutdownConfig
+		minerLeaves   bool
+		expectedMsg   string
+		expectedClose bool
+	}{
+		{
+			name:        "miner reconnects to the same host",
+			shutdown:    config.ShutdownConfig{DrainTimeout: 5 * time.Second},
+			minerLeaves: true,
+			expectedMsg: `{"id":null,"method":"client.reconnect","params":[]}`,
+		},
+		{
+			name:        "miner reconnects to the configured host",
+			shutdown:    config.ShutdownConfig{DrainTimeout: 5 * time.Second, ReconnectHost: "backup.pool.example", ReconnectPort: 3333},
+			minerLeaves: true,
+			expectedMsg: `{"id":null,"method":"client.reconnect","params":["backup.pool.example",3333]}`,
+		},
+		{
+			name:          "miner is dropped after the drain timeout",
+			shutdown:      config.ShutdownConfig{DrainTimeout: 50 * time.Millisecond},
+			expectedMsg:   `{"id":null,"method":"client.reconnect","params":[]}`,
+			expectedClose: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &RepositoryMock{
+				UpdateFunc: func(input repository.UpdateRequest, destinationArgs ...interface{}) error {
+					return nil
+				},
+			}
+			svc := NewService(repo, &config.Config{ShutdownConfig: tt.shutdown}, nil, nil)
+
+			server, client := net.Pipe()
+			ws := NewWebSocket(newTCPTransport(server), svc).(*webSocket)
+			ws.subscription = &subscription{extraNonce1: 1}
+			svc.hub.register(ws)
+			go ws.Read()
+			go ws.Write()
+			go ws.Shutdown()
+
+			shown := make(chan string, 1)
+			received := make(chan string, 1)
+			closed := make(chan struct{})
+			go func() {
+				reader := bufio.NewReader(client)
+				msg, _ := reader.ReadString('\n')
+				shown <- msg
+				msg, _ = reader.ReadString('\n')
+				received <- msg
+				if tt.minerLeaves {
+					client.Close()
+					return
+				}
+				// the server closes the connection
+				if _, err := reader.ReadString('\n'); err == io.EOF {
+					close(closed)
+				}
+			}()
+
+			assert.NoError(t, svc.Drain(context.Background()))
+			assert.JSONEq(t, `{"id":null,"method":"client.show_message","params":["server shutting down, reconnecting"]}`, <-shown)
+			assert.JSONEq(t, tt.expectedMsg, <-received)
+
+			select {
+			case <-ws.done:
+			case <-time.After(time.Second):
+				t.Fatal("connection wasn't shutdown")
+			}
+			if tt.expectedClose {
+				select {
+				case <-closed:
+				case <-time.After(time.Second):
+					t.Fatal("connection wasn't closed by the server")
+				}
+			}
+
+			// subscriptions are marked as inactive at once, not on every connection shutdown
+			calls := repo.UpdateCalls()
+			assert.Len(t, calls, 1)
+			assert.Contains(t, calls[0].Input.Query, "WHERE active_session = true")
+		})
+	}
+}
+
+func TestService_Drain_refusesConnections(t *testing.T) {
+	repo := &RepositoryMock{
+		UpdateFunc: func(input repository.UpdateRequest, destinationArgs ...interface{}) error {
+			return nil
+		},
+	}
+	svc := NewService(repo, &config.Config{ShutdownConfig: config.ShutdownConfig{DrainTimeout: time.Second}}, nil, nil)
+	assert.NoError(t, svc.Drain(context.Background()))
+
+	server, client := net.Pipe()
+	svc.RunTCPConnection(context.Background(), server)
+
+	_, err := client.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+}
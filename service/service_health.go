@@ -1,13 +1,104 @@
 package service
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	dependencyOK    = "ok"
+	dependencyError = "error"
+
+	postgresDependency = "postgres"
+	templateDependency = "template"
+
+	// max time waiting for every dependency check
+	readinessTimeout = 2 * time.Second
+)
 
 type HealthResponse struct {
 	Status int64 `json:"status,omitempty"`
 }
 
+// DependencyStatus represents the result of checking a dependency.
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	// age of the last block template, only reported for the template dependency
+	AgeSeconds *float64 `json:"age_seconds,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// ReadinessResponse represents the status of every dependency needed to serve miners.
+type ReadinessResponse struct {
+	Status       int64                        `json:"status"`
+	Dependencies map[string]*DependencyStatus `json:"dependencies"`
+}
+
 func (s *service) Health() *HealthResponse {
 	return &HealthResponse{
 		Status: http.StatusOK,
 	}
 }
+
+func (s *service) Ready(ctx context.Context) *ReadinessResponse {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	res := &ReadinessResponse{
+		Status: http.StatusOK,
+		Dependencies: map[string]*DependencyStatus{
+			postgresDependency: s.checkPostgres(ctx),
+		},
+	}
+	// jobs are only built from templates when there's a node configured
+	if s.templateSource != nil {
+		res.Dependencies[templateDependency] = s.checkTemplate()
+	}
+
+	for _, dependency := range res.Dependencies {
+		if dependency.Status != dependencyOK {
+			res.Status = http.StatusServiceUnavailable
+		}
+	}
+	return res
+}
+
+func (s *service) checkPostgres(ctx context.Context) *DependencyStatus {
+	start := time.Now()
+	err := s.repository.Ping(ctx)
+	return newDependencyStatus(start, err)
+}
+
+func (s *service) checkTemplate() *DependencyStatus {
+	start := time.Now()
+	// unchanged templates aren't published, so the age is measured from the last successful getblocktemplate
+	fetchedAt := s.templateSource.LastFetchAt()
+	if fetchedAt.IsZero() {
+		return newDependencyStatus(start, fmt.Errorf("no block template received yet"))
+	}
+
+	age := time.Since(fetchedAt)
+	var err error
+	if age > s.templateMaxAge {
+		err = fmt.Errorf("last block template is older than %s", s.templateMaxAge)
+	}
+	status := newDependencyStatus(start, err)
+	ageSeconds := age.Seconds()
+	status.AgeSeconds = &ageSeconds
+	return status
+}
+
+func newDependencyStatus(start time.Time, err error) *DependencyStatus {
+	status := &DependencyStatus{
+		Status:    dependencyOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = dependencyError
+		status.Error = err.Error()
+	}
+	return status
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"stratum-server/config"
+	"stratum-server/template"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nopTemplateSource struct {
+	fetchedAt time.Time
+}
+
+func (nopTemplateSource) Run(context.Context, chan<- *template.Template) {}
+
+func (nopTemplateSource) SubmitBlock(context.Context, []byte) (string, error) {
+	return template.SubmitBlockAccepted, nil
+}
+
+func (s nopTemplateSource) LastFetchAt() time.Time {
+	return s.fetchedAt
+}
+
+func TestService_Ready(t *testing.T) {
+	tests := []struct {
+		name                 string
+		pingErr              error
+		templateSource       template.Source
+		expectedStatus       int64
+		expectedDependencies map[string]string
+		expectedErrors       map[string]string
+	}{
+		{
+			name:                 "ready without node",
+			expectedStatus:       http.StatusOK,
+			expectedDependencies: map[string]string{postgresDependency: dependencyOK},
+		},
+		{
+			name:                 "ready with fresh template",
+			templateSource:       nopTemplateSource{fetchedAt: time.Now().Add(-time.Minute)},
+			expectedStatus:       http.StatusOK,
+			expectedDependencies: map[string]string{postgresDependency: dependencyOK, templateDependency: dependencyOK},
+		},
+		{
+			name:                 "not ready with postgres down",
+			pingErr:              fmt.Errorf("connection refused"),
+			expectedStatus:       http.StatusServiceUnavailable,
+			expectedDependencies: map[string]string{postgresDependency: dependencyError},
+			expectedErrors:       map[string]string{postgresDependency: "connection refused"},
+		},
+		{
+			name:                 "not ready without template",
+			templateSource:       nopTemplateSource{},
+			expectedStatus:       http.StatusServiceUnavailable,
+			expectedDependencies: map[string]string{postgresDependency: dependencyOK, templateDependency: dependencyError},
+			expectedErrors:       map[string]string{templateDependency: "no block template received yet"},
+		},
+		{
+			name:                 "not ready with stale template",
+			templateSource:       nopTemplateSource{fetchedAt: time.Now().Add(-time.Hour)},
+			expectedStatus:       http.StatusServiceUnavailable,
+			expectedDependencies: map[string]string{postgresDependency: dependencyOK, templateDependency: dependencyError},
+			expectedErrors:       map[string]string{templateDependency: "last block template is older than 10m0s"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &RepositoryMock{
+				PingFunc: func(ctx context.Context) error {
+					return tt.pingErr
+				},
+			}
+			svc := NewService(repo, &config.Config{NodeConfig: config.NodeConfig{TemplateMaxAge: 10 * time.Minute}}, tt.templateSource, nil)
+
+			res := svc.Ready(context.Background())
+			assert.Equal(t, tt.expectedStatus, res.Status)
+
+			dependencies := make(map[string]string)
+			errors := make(map[string]string)
+			for name, dependency := range res.Dependencies {
+				dependencies[name] = dependency.Status
+				if dependency.Error != "" {
+					errors[name] = dependency.Error
+				}
+			}
+			assert.Equal(t, tt.expectedDependencies, dependencies)
+			if tt.expectedErrors == nil {
+				tt.expectedErrors = map[string]string{}
+			}
+			assert.Equal(t, tt.expectedErrors, errors)
+		})
+	}
+}
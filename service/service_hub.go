@@ -0,0 +1,99 @@
+package service
+
+import (
+	"sync"
+)
+
+// Hub tracks every live connection holding a subscription, by extraNonce1. Connections are registered
+// on subscribe and unregistered when they're shutdown.
+type Hub struct {
+	mu       sync.RWMutex
+	sessions map[int64]*webSocket
+}
+
+func newHub() *Hub {
+	return &Hub{
+		sessions: make(map[int64]*webSocket),
+	}
+}
+
+func (h *Hub) register(ws *webSocket) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessions[ws.getSubscription().extraNonce1] = ws
+}
+
+func (h *Hub) unregister(ws *webSocket) {
+	if !ws.hasActiveSubscription() {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// the extraNonce1 could already belong to a newer connection
+	extraNonce1 := ws.getSubscription().extraNonce1
+	if h.sessions[extraNonce1] == ws {
+		delete(h.sessions, extraNonce1)
+	}
+}
+
+// reassign: moves the connection to the extraNonce1 of its new subscription, returning false if it already belongs to
+// another connection
+func (h *Hub) reassign(ws *webSocket, sub *subscription, extraNonce2 int64) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if other, ok := h.sessions[sub.extraNonce1]; ok && other != ws {
+		return false
+	}
+
+	// the connection state is replaced while holding the lock, so that unregister never misses the new key
+	extraNonce1 := ws.getSubscription().extraNonce1
+	if h.sessions[extraNonce1] == ws {
+		delete(h.sessions, extraNonce1)
+	}
+	ws.setExtraNonce(sub, extraNonce2)
+	h.sessions[sub.extraNonce1] = ws
+	return true
+}
+
+// get: returns the connection subscribed with the given extraNonce1, nil if there's none
+func (h *Hub) get(extraNonce1 int64) *webSocket {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.sessions[extraNonce1]
+}
+
+// getByWorker: returns every connection where the worker is authorized
+func (h *Hub) getByWorker(name string) []*webSocket {
+	var sessions []*webSocket
+	h.forEach(func(ws *webSocket) {
+		if ws.getWorker(name) != nil {
+			sessions = append(sessions, ws)
+		}
+	})
+	return sessions
+}
+
+// forEach: calls fn for every registered connection, without holding the lock so that fn can be slow
+func (h *Hub) forEach(fn func(ws *webSocket)) {
+	for _, ws := range h.list() {
+		fn(ws)
+	}
+}
+
+func (h *Hub) list() []*webSocket {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sessions := make([]*webSocket, 0, len(h.sessions))
+	for _, ws := range h.sessions {
+		sessions = append(sessions, ws)
+	}
+	return sessions
+}
+
+func (h *Hub) count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.sessions)
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newHubSession(extraNonce1 int64, workers ...string) *webSocket {
+	ws := &webSocket{
+		subscription: &subscription{extraNonce1: extraNonce1},
+		workers:      make(map[string]*worker),
+	}
+	for _, name := range workers {
+		ws.addWorker(&worker{name: name})
+	}
+	return ws
+}
+
+func TestHub(t *testing.T) {
+	hub := newHub()
+	first := newHubSession(1, "alice.rig1")
+	second := newHubSession(2, "alice.rig1", "bob")
+
+	hub.register(first)
+	hub.register(second)
+	assert.Equal(t, 2, hub.count())
+	assert.Equal(t, first, hub.get(1))
+	assert.Nil(t, hub.get(3))
+	assert.ElementsMatch(t, []*webSocket{first, second}, hub.getByWorker("alice.rig1"))
+	assert.Equal(t, []*webSocket{second}, hub.getByWorker("bob"))
+	assert.Empty(t, hub.getByWorker("carol"))
+
+	var visited []*webSocket
+	hub.forEach(func(ws *webSocket) {
+		visited = append(visited, ws)
+	})
+	assert.ElementsMatch(t, []*webSocket{first, second}, visited)
+
+	hub.unregister(first)
+	assert.Equal(t, 1, hub.count())
+	assert.Nil(t, hub.get(1))
+}
+
+func TestHub_unregister(t *testing.T) {
+	hub := newHub()
+	previous := newHubSession(1)
+	resumed := newHubSession(1)
+
+	// the subscription was resumed by a new connection before the previous one was shutdown
+	hub.register(previous)
+	hub.register(resumed)
+	hub.unregister(previous)
+	assert.Equal(t, resumed, hub.get(1))
+
+	// connections that never subscribed are ignored
+	hub.unregister(&webSocket{})
+	assert.Equal(t, 1, hub.count())
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"stratum-server/repository"
+	"time"
+)
+
+// RegisterInstance: records the instance heartbeat and frees the sessions it held before restarting, which are never
+// marked as inactive when the process crashes. It must be called before accepting connections.
+func (s *service) RegisterInstance() error {
+	if err := s.heartbeat(); err != nil {
+		return err
+	}
+	return s.inactiveSubscriptions()
+}
+
+// RunHeartbeat: periodically refreshes the instance heartbeat and frees the sessions held by instances that stopped
+// heartbeating
+func (s *service) RunHeartbeat(ctx context.Context) {
+	ticker := time.NewTicker(s.instanceConfig.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.heartbeat(); err != nil {
+				continue
+			}
+			_ = s.reapSubscriptions()
+		}
+	}
+}
+
+func (s *service) heartbeat() error {
+	sqlStatement := fmt.Sprintf(`
+	INSERT INTO %s.%s (id)
+	VALUES ($1)
+	ON CONFLICT (id) DO UPDATE SET heartbeat_at = now()
+	RETURNING heartbeat_at`, s.instancesTable.Schema, s.instancesTable.Name)
+
+	var heartbeatAt time.Time
+	if err := s.repository.Insert(repository.InsertRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			s.instanceConfig.ID,
+		},
+	}, &heartbeatAt); err != nil {
+		log.Printf("error updating instance heartbeat: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// reapSubscriptions: frees the active subscriptions whose instance didn't heartbeat within the timeout, so that they
+// can be resumed. Subscriptions without instance are from before instances were tracked.
+func (s *service) reapSubscriptions() error {
+	sqlStatement := fmt.Sprintf(`
+	WITH reaped AS (
+		UPDATE %s.%s AS s
+		SET active_session = false
+		WHERE s.active_session = true
+		AND NOT EXISTS (
+			SELECT 1 FROM %s.%s AS i
+			WHERE i.id = s.instance_id
+			AND i.heartbeat_at > now() - $1 * INTERVAL '1 second'
+		)
+		RETURNING s.extra_nonce_1
+	)
+	SELECT COUNT(*) FROM reaped`,
+		s.subscriptionsTable.Schema, s.subscriptionsTable.Name, s.instancesTable.Schema, s.instancesTable.Name)
+
+	var count int64
+	if err := s.repository.Update(repository.UpdateRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			s.instanceConfig.HeartbeatTimeout.Seconds(),
+		},
+	}, &count); err != nil {
+		log.Printf("error reaping subscriptions: %v", err)
+		return err
+	}
+
+	if count > 0 {
+		log.Printf("%d subscriptions of stopped instances marked as inactive", count)
+	}
+	return nil
+}
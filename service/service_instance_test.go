@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"stratum-server/config"
+	"stratum-server/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_RegisterInstance(t *testing.T) {
+	tests := []struct {
+		name          string
+		heartbeatErr  error
+		expectedError error
+		expectedReset bool
+	}{
+		{
+			name:          "orphaned sessions are reset",
+			expectedReset: true,
+		},
+		{
+			name:          "error without heartbeat",
+			heartbeatErr:  fmt.Errorf("connection refused"),
+			expectedError: fmt.Errorf("connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &RepositoryMock{
+				InsertFunc: func(input repository.InsertRequest, destinationArgs ...interface{}) error {
+					return tt.heartbeatErr
+				},
+				UpdateFunc: func(input repository.UpdateRequest, destinationArgs ...interface{}) error {
+					return nil
+				},
+			}
+			svc := NewService(repo, &config.Config{InstanceConfig: config.InstanceConfig{ID: "stratum-0"}}, nil, nil)
+
+			assert.Equal(t, tt.expectedError, svc.RegisterInstance())
+			assert.Equal(t, []interface{}{"stratum-0"}, repo.InsertCalls()[0].Input.Args)
+			if !tt.expectedReset {
+				assert.Empty(t, repo.UpdateCalls())
+				return
+			}
+			assert.Len(t, repo.UpdateCalls(), 1)
+			assert.Equal(t, []interface{}{"stratum-0"}, repo.UpdateCalls()[0].Input.Args)
+			assert.Contains(t, repo.UpdateCalls()[0].Input.Query, "AND instance_id = $1")
+		})
+	}
+}
+
+func TestService_reapSubscriptions(t *testing.T) {
+	repo := &RepositoryMock{
+		UpdateFunc: func(input repository.UpdateRequest, destinationArgs ...interface{}) error {
+			*destinationArgs[0].(*int64) = 3
+			return nil
+		},
+	}
+	svc := NewService(repo, &config.Config{
+		PostgreSQLConfig: config.PostgreSQLConfig{
+			SubscriptionsTable: config.PostgreSQLTableConfig{Schema: "public", Name: "subscriptions"},
+			InstancesTable:     config.PostgreSQLTableConfig{Schema: "public", Name: "instances"},
+		},
+		InstanceConfig: config.InstanceConfig{ID: "stratum-0", HeartbeatTimeout: 90 * time.Second},
+	}, nil, nil)
+
+	assert.NoError(t, svc.reapSubscriptions())
+	assert.Len(t, repo.UpdateCalls(), 1)
+	assert.Equal(t, []interface{}{90.0}, repo.UpdateCalls()[0].Input.Args)
+	assert.Contains(t, repo.UpdateCalls()[0].Input.Query, "UPDATE public.subscriptions AS s")
+	assert.Contains(t, repo.UpdateCalls()[0].Input.Query, "SELECT 1 FROM public.instances AS i")
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"stratum-server/template"
+	"sync"
+	"time"
+)
+
+const (
+	// amount of jobs kept in memory, older ones are considered stale
+	maxJobs = 16
+)
+
+// Job represents a mining job as sent in the mining.notify params. All the
+// fields are hex encoded following the Stratum V1 conventions.
+type Job struct {
+	ID           string
+	PrevHash     string
+	Coinb1       string
+	Coinb2       string
+	MerkleBranch []string
+	Version      string
+	NBits        string
+	NTime        string
+	CleanJobs    bool
+
+	createdAt time.Time
+	// template the job was built from, nil for jobs published manually
+	blockTemplate *template.Template
+	// shares already submitted for this job, used to detect duplicates
+	mu          sync.Mutex
+	submissions map[string]struct{}
+}
+
+type jobManager struct {
+	mu      sync.RWMutex
+	counter uint64
+	current *Job
+	jobs    map[string]*Job
+	order   []string
+}
+
+func newJobManager() *jobManager {
+	return &jobManager{
+		jobs: make(map[string]*Job),
+	}
+}
+
+// PublishJob: stores the job as the current one and notifies it to every subscribed connection
+func (s *service) PublishJob(job *Job) {
+	s.jobs.add(job)
+	log.Printf("[mining.notify] broadcasting job %s", job.ID)
+
+	s.hub.forEach(func(ws *webSocket) {
+		ws.sendJob(job, job.CleanJobs)
+	})
+}
+
+func (jm *jobManager) add(job *Job) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	jm.counter++
+	if job.ID == "" {
+		job.ID = fmt.Sprintf("%x", jm.counter)
+	}
+	job.createdAt = time.Now()
+	job.submissions = make(map[string]struct{})
+
+	if job.CleanJobs {
+		jm.jobs = make(map[string]*Job)
+		jm.order = nil
+	}
+	jm.jobs[job.ID] = job
+	jm.order = append(jm.order, job.ID)
+	for len(jm.order) > maxJobs {
+		delete(jm.jobs, jm.order[0])
+		jm.order = jm.order[1:]
+	}
+	jm.current = job
+}
+
+func (jm *jobManager) get(id string) *Job {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	return jm.jobs[id]
+}
+
+func (jm *jobManager) currentJob() *Job {
+	jm.mu.RLock()
+	defer jm.mu.RUnlock()
+	return jm.current
+}
+
+// addSubmission: returns false if the share was already submitted
+func (j *Job) addSubmission(key string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.submissions[key]; ok {
+		return false
+	}
+	j.submissions[key] = struct{}{}
+	return true
+}
+
+// notifyParams: builds the mining.notify params with the coinbase parts of the connection
+func (j *Job) notifyParams(coinb1 string, coinb2 string, cleanJobs bool) []interface{} {
+	merkleBranch := j.MerkleBranch
+	if merkleBranch == nil {
+		merkleBranch = []string{}
+	}
+	return []interface{}{
+		j.ID,
+		j.PrevHash,
+		coinb1,
+		coinb2,
+		merkleBranch,
+		j.Version,
+		j.NBits,
+		j.NTime,
+		cleanJobs,
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"stratum-server/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobManager_add(t *testing.T) {
+	tests := []struct {
+		name            string
+		jobs            []*Job
+		expectedIDs     []string
+		expectedStale   []string
+		expectedCurrent string
+	}{
+		{
+			name:            "assigns increasing hex ids",
+			jobs:            newJobs(11),
+			expectedIDs:     []string{"1", "2", "9", "a", "b"},
+			expectedCurrent: "b",
+		},
+		{
+			name:            "keeps the given id",
+			jobs:            []*Job{{}, {ID: "custom"}},
+			expectedIDs:     []string{"1", "custom"},
+			expectedCurrent: "custom",
+		},
+		{
+			name:            "evicts the oldest jobs",
+			jobs:            newJobs(maxJobs + 2),
+			expectedIDs:     []string{"3", fmt.Sprintf("%x", maxJobs+2)},
+			expectedStale:   []string{"1", "2"},
+			expectedCurrent: fmt.Sprintf("%x", maxJobs+2),
+		},
+		{
+			name:            "clean jobs evict every previous job",
+			jobs:            append(newJobs(3), &Job{CleanJobs: true}),
+			expectedIDs:     []string{"4"},
+			expectedStale:   []string{"1", "2", "3"},
+			expectedCurrent: "4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jm := newJobManager()
+			assert.Nil(t, jm.currentJob())
+
+			for _, job := range tt.jobs {
+				jm.add(job)
+			}
+			for _, id := range tt.expectedIDs {
+				if assert.NotNil(t, jm.get(id), id) {
+					assert.Equal(t, id, jm.get(id).ID)
+				}
+			}
+			for _, id := range tt.expectedStale {
+				assert.Nil(t, jm.get(id), id)
+			}
+			assert.Equal(t, tt.expectedCurrent, jm.currentJob().ID)
+		})
+	}
+}
+
+func TestJob_addSubmission(t *testing.T) {
+	jm := newJobManager()
+	job := &Job{}
+	jm.add(job)
+
+	assert.True(t, job.addSubmission("share"))
+	assert.False(t, job.addSubmission("share"))
+	assert.True(t, job.addSubmission("other"))
+}
+
+// newJobs: jobs without ids, so that they get consecutive ones
+func newJobs(n int) []*Job {
+	jobs := make([]*Job, n)
+	for i := range jobs {
+		jobs[i] = &Job{}
+	}
+	return jobs
+}
+
+func TestService_PublishJob(t *testing.T) {
+	tests := []struct {
+		name      string
+		cleanJobs bool
+	}{
+		{
+			name:      "clean jobs",
+			cleanJobs: true,
+		},
+		{
+			name: "jobs on the same block",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(nil, &config.Config{}, nil, nil)
+			newSession := func(extraNonce1 int64) *webSocket {
+				ws := newHubSession(extraNonce1)
+				ws.svc = svc
+				ws.inboundMsg = make(chan []byte, 4)
+				return ws
+			}
+			subscribed := []*webSocket{newSession(1), newSession(2), newSession(3)}
+			for _, ws := range subscribed {
+				svc.hub.register(ws)
+			}
+			// connections are only registered once subscribed, and unregistered when closed
+			unsubscribed := newSession(0)
+			unsubscribed.subscription = nil
+			closed := newSession(4)
+			svc.hub.register(closed)
+			svc.hub.unregister(closed)
+
+			job := &Job{
+				PrevHash:  "0000000000000000000000000000000000000000000000000000000000000000",
+				Coinb1:    genesisCoinb1,
+				Coinb2:    genesisCoinb2,
+				Version:   "00000001",
+				NBits:     "1d00ffff",
+				NTime:     genesisNTime,
+				CleanJobs: tt.cleanJobs,
+			}
+			svc.PublishJob(job)
+			assert.Equal(t, job, svc.jobs.currentJob())
+
+			expected, err := json.Marshal([]interface{}{job.ID, job.PrevHash, genesisCoinb1, genesisCoinb2, []string{},
+				"00000001", "1d00ffff", genesisNTime, tt.cleanJobs})
+			assert.NoError(t, err)
+			for _, ws := range subscribed {
+				if assert.Len(t, ws.inboundMsg, 1) {
+					var msg struct {
+						Method string          `json:"method"`
+						Params json.RawMessage `json:"params"`
+					}
+					assert.NoError(t, json.Unmarshal(<-ws.inboundMsg, &msg))
+					assert.Equal(t, miningNotifyKey, msg.Method)
+					assert.JSONEq(t, string(expected), string(msg.Params))
+				}
+			}
+			assert.Len(t, unsubscribed.inboundMsg, 0)
+			assert.Len(t, closed.inboundMsg, 0)
+		})
+	}
+}
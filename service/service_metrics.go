@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	metricsInterval = 15 * time.Second
+	// period used to estimate the pool hashrate
+	hashrateWindow = 5 * time.Minute
+)
+
+// hashrateEstimator estimates the pool hashrate from the difficulty of the accepted shares.
+type hashrateEstimator struct {
+	mu         sync.Mutex
+	window     time.Duration
+	difficulty []float64
+	at         []time.Time
+}
+
+func newHashrateEstimator(window time.Duration) *hashrateEstimator {
+	return &hashrateEstimator{
+		window: window,
+	}
+}
+
+func (h *hashrateEstimator) addShare(difficulty float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.difficulty = append(h.difficulty, difficulty)
+	h.at = append(h.at, time.Now())
+}
+
+// estimate: every difficulty 1 share takes 2^32 hashes on average
+func (h *hashrateEstimator) estimate(now time.Time) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// shares older than the window are discarded
+	start := 0
+	for start < len(h.at) && now.Sub(h.at[start]) > h.window {
+		start++
+	}
+	h.difficulty = h.difficulty[start:]
+	h.at = h.at[start:]
+
+	total := 0.0
+	for _, difficulty := range h.difficulty {
+		total += difficulty
+	}
+	return total * math.Pow(2, 32) / h.window.Seconds()
+}
+
+// RunMetrics: periodically updates the metrics that aren't tracked on every event
+func (s *service) RunMetrics(ctx context.Context) {
+	ticker := time.NewTicker(metricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.metrics.SetHashrate(s.hashrate.estimate(now))
+
+			depth := 0
+			s.hub.forEach(func(ws *webSocket) {
+				depth += len(ws.inboundMsg)
+			})
+			s.metrics.SetQueueDepth(depth)
+		}
+	}
+}
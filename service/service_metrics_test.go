@@ -0,0 +1,25 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashrateEstimator_estimate(t *testing.T) {
+	h := newHashrateEstimator(time.Minute)
+	now := time.Now()
+
+	assert.Equal(t, 0.0, h.estimate(now))
+
+	// 60 shares of difficulty 1 in a minute are 2^32 hashes per second
+	for i := 0; i < 60; i++ {
+		h.addShare(1)
+	}
+	assert.Equal(t, float64(1<<32), h.estimate(now))
+
+	// shares are discarded once they're out of the window
+	assert.Equal(t, 0.0, h.estimate(now.Add(2*time.Minute)))
+	assert.Empty(t, h.at)
+}
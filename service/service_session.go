@@ -0,0 +1,181 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// max time waiting for a closed session to be shutdown
+	closeSessionTimeout = 10 * time.Second
+	// max bytes of extraNonce2 the miner can roll
+	maxExtraNonce2 int64 = 8
+	// new subscriptions get their extraNonce1 from a SERIAL, which never reaches this value, so the ones above it are
+	// only handed out by reassignments
+	minReassignedExtraNonce1 int64 = 1 << 31
+)
+
+var (
+	errSessionNotFound              = fmt.Errorf("session not found")
+	errSessionClose                 = fmt.Errorf("session close timed out")
+	errSessionExtraNonceUnsupported = fmt.Errorf("session not subscribed to extranonce changes")
+	errSessionExtraNonceInUse       = fmt.Errorf("extraNonce1 already in use")
+	errSessionInvalidExtraNonce1    = fmt.Errorf("invalid extraNonce1")
+	errSessionInvalidExtraNonce2    = fmt.Errorf("invalid extraNonce2 size")
+)
+
+// Session represents a live subscribed connection.
+type Session struct {
+	RemoteAddr           string    `json:"remote_addr"`
+	Transport            string    `json:"transport"`
+	Subscriber           string    `json:"subscriber"`
+	ExtraNonce1          string    `json:"extra_nonce_1"`
+	ExtraNonce2          int64     `json:"extra_nonce_2"`
+	ExtraNonceSubscribed bool      `json:"extra_nonce_subscribed"`
+	Workers              []string  `json:"workers"`
+	Difficulty           float64   `json:"difficulty"`
+	ConnectedAt          time.Time `json:"connected_at"`
+	SharesAccepted       uint64    `json:"shares_accepted"`
+	SharesRejected       uint64    `json:"shares_rejected"`
+}
+
+func (s *service) GetSessions() []*Session {
+	sessions := make([]*Session, 0, s.hub.count())
+	s.hub.forEach(func(ws *webSocket) {
+		sessions = append(sessions, ws.session())
+	})
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ExtraNonce1 < sessions[j].ExtraNonce1
+	})
+	return sessions
+}
+
+func (s *service) GetSession(extraNonce1 int64) (*Session, *AppError) {
+	ws := s.hub.get(extraNonce1)
+	if ws == nil {
+		return nil, &AppError{
+			Error:   errSessionNotFound,
+			Message: fmt.Sprintf("no session found for extraNonce1 %08x", extraNonce1),
+			Code:    http.StatusNotFound,
+		}
+	}
+	return ws.session(), nil
+}
+
+func (s *service) CloseSession(extraNonce1 int64) *AppError {
+	ws := s.hub.get(extraNonce1)
+	if ws == nil {
+		return &AppError{
+			Error:   errSessionNotFound,
+			Message: fmt.Sprintf("no session found for extraNonce1 %08x", extraNonce1),
+			Code:    http.StatusNotFound,
+		}
+	}
+
+	log.Printf("closing session for extraNonce1 %08x", extraNonce1)
+	ws.CloseConn()
+
+	// the subscription is marked as inactive once the connection is shutdown
+	select {
+	case <-ws.done:
+		return nil
+	case <-time.After(closeSessionTimeout):
+		return &AppError{
+			Error:   errSessionClose,
+			Message: fmt.Sprintf("session for extraNonce1 %08x is still being closed", extraNonce1),
+			Code:    http.StatusInternalServerError,
+		}
+	}
+}
+
+func (s *service) SetExtraNonce(extraNonce1, newExtraNonce1, extraNonce2 int64) (*Session, *AppError) {
+	ws := s.hub.get(extraNonce1)
+	if ws == nil {
+		return nil, &AppError{
+			Error:   errSessionNotFound,
+			Message: fmt.Sprintf("no session found for extraNonce1 %08x", extraNonce1),
+			Code:    http.StatusNotFound,
+		}
+	}
+	if !ws.isExtraNonceSubscribed() {
+		return nil, &AppError{
+			Error:   errSessionExtraNonceUnsupported,
+			Message: fmt.Sprintf("session for extraNonce1 %08x didn't send mining.extranonce.subscribe", extraNonce1),
+			Code:    http.StatusConflict,
+		}
+	}
+	// the extraNonce1 is always sent and rolled as 4 bytes, and new subscriptions never get one of the reserved range
+	if newExtraNonce1 != extraNonce1 && (newExtraNonce1 < minReassignedExtraNonce1 || newExtraNonce1 > math.MaxUint32) {
+		return nil, &AppError{
+			Error: errSessionInvalidExtraNonce1,
+			Message: fmt.Sprintf("extraNonce1 %x is outside of the reassignable range %08x-%08x",
+				newExtraNonce1, minReassignedExtraNonce1, uint32(math.MaxUint32)),
+			Code: http.StatusBadRequest,
+		}
+	}
+	// in pool mode every job shares the same coinbase, which only fits the default extraNonce2 size
+	if extraNonce2 < 1 || extraNonce2 > maxExtraNonce2 || (!s.isSoloMode() && extraNonce2 != s.GetExtraNonce2()) {
+		return nil, &AppError{
+			Error:   errSessionInvalidExtraNonce2,
+			Message: fmt.Sprintf("extraNonce2 size %d is not supported", extraNonce2),
+			Code:    http.StatusBadRequest,
+		}
+	}
+	errInUse := &AppError{
+		Error:   errSessionExtraNonceInUse,
+		Message: fmt.Sprintf("extraNonce1 %08x is already in use", newExtraNonce1),
+		Code:    http.StatusConflict,
+	}
+	if other := s.hub.get(newExtraNonce1); other != nil && other != ws {
+		return nil, errInUse
+	}
+
+	sub, err := s.reassignSubscription(ws.getSubscription(), newExtraNonce1, extraNonce2)
+	if err != nil {
+		return nil, &AppError{
+			Error:   err,
+			Message: fmt.Sprintf("error reassigning session for extraNonce1 %08x", extraNonce1),
+			Code:    http.StatusInternalServerError,
+		}
+	}
+	if sub == nil || !s.hub.reassign(ws, sub, extraNonce2) {
+		return nil, errInUse
+	}
+
+	log.Printf("session for extraNonce1 %08x reassigned to extraNonce1 %08x", extraNonce1, newExtraNonce1)
+	ws.sendExtraNonce(sub)
+	return ws.session(), nil
+}
+
+// session: returns a snapshot of the connection state
+func (ws *webSocket) session() *Session {
+	ws.sessionMu.RLock()
+	workers := make([]string, 0, len(ws.workers))
+	for name := range ws.workers {
+		workers = append(workers, name)
+	}
+	sub := ws.subscription
+	extraNonce2 := ws.extraNonce2
+	extraNonceSubscribed := ws.extraNonceSubscribed
+	ws.sessionMu.RUnlock()
+	sort.Strings(workers)
+
+	return &Session{
+		RemoteAddr:           ws.conn.RemoteAddr().String(),
+		Transport:            ws.conn.Name(),
+		Subscriber:           sub.subscriber,
+		ExtraNonce1:          fmt.Sprintf("%08x", sub.extraNonce1),
+		ExtraNonce2:          extraNonce2,
+		ExtraNonceSubscribed: extraNonceSubscribed,
+		Workers:              workers,
+		Difficulty:           ws.vardiff.currentDifficulty(),
+		ConnectedAt:          ws.connectedAt,
+		SharesAccepted:       atomic.LoadUint64(&ws.sharesAccepted),
+		SharesRejected:       atomic.LoadUint64(&ws.sharesRejected),
+	}
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"stratum-server/config"
+	"stratum-server/repository"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_SetExtraNonce(t *testing.T) {
+	tests := []struct {
+		name                 string
+		poolMode             string
+		extraNonceSubscribed bool
+		extraNonce1          int64
+		newExtraNonce1       int64
+		extraNonce2          int64
+		otherSession         bool
+		updateErr            error
+		expectedCode         int
+		expectedNotification string
+		expectedExtraNonce1  int64
+		expectedExtraNonce2  int64
+	}{
+		{
+			name:                 "reassigned extraNonce1",
+			extraNonceSubscribed: true,
+			extraNonce1:          1,
+			newExtraNonce1:       0x8000002a,
+			extraNonce2:          4,
+			expectedNotification: `{"id":null,"method":"mining.set_extranonce","params":["8000002a",4]}`,
+			expectedExtraNonce1:  0x8000002a,
+			expectedExtraNonce2:  4,
+		},
+		{
+			name:                 "reassigned extraNonce2 size in solo mode",
+			poolMode:             config.MiningModeSolo,
+			extraNonceSubscribed: true,
+			extraNonce1:          1,
+			newExtraNonce1:       1,
+			extraNonce2:          2,
+			expectedNotification: `{"id":null,"method":"mining.set_extranonce","params":["00000001",2]}`,
+			expectedExtraNonce1:  1,
+			expectedExtraNonce2:  2,
+		},
+		{
+			name:                 "error with session not found",
+			extraNonceSubscribed: true,
+			extraNonce1:          3,
+			newExtraNonce1:       0x8000002a,
+			extraNonce2:          4,
+			expectedCode:         http.StatusNotFound,
+			expectedExtraNonce1:  1,
+			expectedExtraNonce2:  4,
+		},
+		{
+			name:                "error with session not subscribed to extranonce",
+			extraNonce1:         1,
+			newExtraNonce1:      0x8000002a,
+			extraNonce2:         4,
+			expectedCode:        http.StatusConflict,
+			expectedExtraNonce1: 1,
+			expectedExtraNonce2: 4,
+		},
+		{
+			name:                 "error with extraNonce1 wider than 4 bytes",
+			extraNonceSubscribed: true,
+			extraNonce1:          1,
+			newExtraNonce1:       0x100000000,
+			extraNonce2:          4,
+			expectedCode:         http.StatusBadRequest,
+			expectedExtraNonce1:  1,
+			expectedExtraNonce2:  4,
+		},
+		{
+			name:                 "error with extraNonce1 outside of the reassignable range",
+			extraNonceSubscribed: true,
+			extraNonce1:          1,
+			newExtraNonce1:       0x2a,
+			extraNonce2:          4,
+			expectedCode:         http.StatusBadRequest,
+			expectedExtraNonce1:  1,
+			expectedExtraNonce2:  4,
+		},
+		{
+			name:                 "error with extraNonce2 size in pool mode",
+			extraNonceSubscribed: true,
+			extraNonce1:          1,
+			newExtraNonce1:       0x8000002a,
+			extraNonce2:          2,
+			expectedCode:         http.StatusBadRequest,
+			expectedExtraNonce1:  1,
+			expectedExtraNonce2:  4,
+		},
+		{
+			name:                 "error with extraNonce2 size too big",
+			poolMode:             config.MiningModeSolo,
+			extraNonceSubscribed: true,
+			extraNonce1:          1,
+			newExtraNonce1:       0x8000002a,
+			extraNonce2:          9,
+			expectedCode:         http.StatusBadRequest,
+			expectedExtraNonce1:  1,
+			expectedExtraNonce2:  4,
+		},
+		{
+			name:                 "error with extraNonce1 of another session",
+			extraNonceSubscribed: true,
+			extraNonce1:          1,
+			newExtraNonce1:       0x80000002,
+			extraNonce2:          4,
+			otherSession:         true,
+			expectedCode:         http.StatusConflict,
+			expectedExtraNonce1:  1,
+			expectedExtraNonce2:  4,
+		},
+		{
+			name:                 "error with extraNonce1 of another subscription",
+			extraNonceSubscribed: true,
+			extraNonce1:          1,
+			newExtraNonce1:       0x8000002a,
+			extraNonce2:          4,
+			updateErr:            sql.ErrNoRows,
+			expectedCode:         http.StatusConflict,
+			expectedExtraNonce1:  1,
+			expectedExtraNonce2:  4,
+		},
+		{
+			name:                 "error with extraNonce1 taken concurrently",
+			extraNonceSubscribed: true,
+			extraNonce1:          1,
+			newExtraNonce1:       0x8000002a,
+			extraNonce2:          4,
+			updateErr:            &pq.Error{Code: "23505"},
+			expectedCode:         http.StatusConflict,
+			expectedExtraNonce1:  1,
+			expectedExtraNonce2:  4,
+		},
+		{
+			name:                 "error updating subscription",
+			extraNonceSubscribed: true,
+			extraNonce1:          1,
+			newExtraNonce1:       0x8000002a,
+			extraNonce2:          4,
+			updateErr:            fmt.Errorf("connection refused"),
+			expectedCode:         http.StatusInternalServerError,
+			expectedExtraNonce1:  1,
+			expectedExtraNonce2:  4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &RepositoryMock{
+				UpdateFunc: func(input repository.UpdateRequest, destinationArgs ...interface{}) error {
+					if tt.updateErr != nil {
+						return tt.updateErr
+					}
+					*destinationArgs[0].(*int64) = input.Args[0].(int64)
+					*destinationArgs[1].(*int64) = input.Args[1].(int64)
+					return nil
+				},
+			}
+			svc := NewService(repo, &config.Config{PoolConfig: config.PoolConfig{Mode: tt.poolMode}}, nil, nil)
+
+			server, client := net.Pipe()
+			defer client.Close()
+			ws := NewWebSocket(newTCPTransport(server), svc).(*webSocket)
+			ws.subscription = &subscription{extraNonce1: 1, extraNonce2: 4}
+			ws.extraNonceSubscribed = tt.extraNonceSubscribed
+			svc.hub.register(ws)
+			if tt.otherSession {
+				svc.hub.register(newHubSession(tt.newExtraNonce1))
+			}
+
+			session, err := svc.SetExtraNonce(tt.extraNonce1, tt.newExtraNonce1, tt.extraNonce2)
+			if tt.expectedCode != 0 {
+				assert.Nil(t, session)
+				assert.Equal(t, tt.expectedCode, err.Code)
+				assertNoPendingMessages(t, ws)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, fmt.Sprintf("%08x", tt.expectedExtraNonce1), session.ExtraNonce1)
+				assert.Equal(t, tt.expectedExtraNonce2, session.ExtraNonce2)
+				assert.Equal(t, tt.expectedNotification, string(<-ws.inboundMsg))
+				if tt.expectedExtraNonce1 != tt.extraNonce1 {
+					assert.Nil(t, svc.hub.get(tt.extraNonce1))
+				}
+			}
+			assert.Equal(t, ws, svc.hub.get(tt.expectedExtraNonce1))
+			assert.Equal(t, tt.expectedExtraNonce1, ws.getSubscription().extraNonce1)
+			assert.Equal(t, tt.expectedExtraNonce2, ws.getExtraNonce2())
+		})
+	}
+}
+
+// TestService_SetExtraNonce_concurrent: reassigns the extranonce while the connection is submitting shares, meant to
+// be run with -race
+func TestService_SetExtraNonce_concurrent(t *testing.T) {
+	const reassignments = 50
+
+	repo := newSubscriptionsRepository(1)
+	repo.UpdateFunc = func(input repository.UpdateRequest, destinationArgs ...interface{}) error {
+		// the subscription is marked as inactive once the connection is closed
+		if len(destinationArgs) == 1 {
+			return nil
+		}
+		*destinationArgs[0].(*int64) = input.Args[0].(int64)
+		*destinationArgs[1].(*int64) = input.Args[1].(int64)
+		return nil
+	}
+	svc := NewService(repo, &config.Config{AuthMode: config.AuthModeNone}, nil, nil)
+	svc.jobs.add(&Job{
+		ID:        "1",
+		PrevHash:  "0000000000000000000000000000000000000000000000000000000000000000",
+		Coinb1:    genesisCoinb1,
+		Coinb2:    genesisCoinb2,
+		Version:   "00000001",
+		NBits:     "1d00ffff",
+		NTime:     genesisNTime,
+		CleanJobs: true,
+	})
+
+	server, client := net.Pipe()
+	ws := NewWebSocket(newTCPTransport(server), svc).(*webSocket)
+	go ws.Read()
+	go ws.Write()
+	go ws.Shutdown()
+	go io.Copy(io.Discard, bufio.NewReader(client))
+
+	for _, msg := range []string{
+		`{"id":1,"method":"mining.subscribe","params":[]}`,
+		`{"id":2,"method":"mining.extranonce.subscribe","params":[]}`,
+		`{"id":3,"method":"mining.authorize","params":["alice.rig1",""]}`,
+	} {
+		_, err := client.Write([]byte(msg + "\n"))
+		assert.NoError(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		return svc.hub.get(1) != nil && ws.isExtraNonceSubscribed()
+	}, time.Second, time.Millisecond)
+
+	submitted := make(chan struct{})
+	go func() {
+		defer close(submitted)
+		for i := 0; i < reassignments; i++ {
+			msg := fmt.Sprintf(`{"id":%d,"method":"mining.submit","params":["alice.rig1","1","%08x","%s","%s"]}`, i+4, i, genesisNTime, genesisNonce)
+			if _, err := client.Write([]byte(msg + "\n")); err != nil {
+				return
+			}
+		}
+	}()
+	extraNonce1 := int64(1)
+	for i := 0; i < reassignments; i++ {
+		session, err := svc.SetExtraNonce(extraNonce1, minReassignedExtraNonce1+int64(i), 4)
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("%08x", minReassignedExtraNonce1+int64(i)), session.ExtraNonce1)
+		extraNonce1 = minReassignedExtraNonce1 + int64(i)
+	}
+	<-submitted
+
+	client.Close()
+	<-ws.done
+	assert.Nil(t, svc.hub.get(extraNonce1))
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"stratum-server/bitcoin"
+	"strings"
+)
+
+const (
+	// amount of params in mining.submit: worker, job_id, extranonce2, ntime and nonce
+	miningSubmitParams = 5
+	// version_bits is sent as an extra param when version rolling is negotiated
+	miningSubmitVersionRollingParams = 6
+)
+
+var (
+	errShareNotSubscribed = fmt.Errorf("connection not subscribed")
+	errShareUnauthorized  = fmt.Errorf("worker not authorized")
+	errShareMalformed     = fmt.Errorf("malformed share")
+	errShareStale         = fmt.Errorf("stale share")
+	errShareDuplicate     = fmt.Errorf("duplicate share")
+	errShareLowDifficulty = fmt.Errorf("low difficulty share")
+	errShareVersionBits   = fmt.Errorf("version bits outside of the negotiated mask")
+)
+
+type share struct {
+	worker      string
+	jobID       string
+	extraNonce2 string
+	nTime       string
+	nonce       string
+	// empty when the version isn't rolled
+	versionBits string
+}
+
+type shareResult struct {
+	// subscription the share was validated with, which could be reassigned meanwhile
+	subscription *subscription
+	job          *Job
+	coinbase     []byte
+	header       *bitcoin.BlockHeader
+	hash         []byte
+	// difficulty the share was validated against
+	difficulty float64
+	// the hash also meets the network target
+	isBlock bool
+}
+
+func parseShare(raw json.RawMessage) (*share, error) {
+	params, n, err := stringParams(raw, miningSubmitVersionRollingParams)
+	if err != nil || n < miningSubmitParams {
+		return nil, errShareMalformed
+	}
+	for _, p := range params[:miningSubmitParams] {
+		if p == "" {
+			return nil, errShareMalformed
+		}
+	}
+
+	return &share{
+		worker:      params[0],
+		jobID:       params[1],
+		extraNonce2: strings.ToLower(params[2]),
+		nTime:       strings.ToLower(params[3]),
+		nonce:       strings.ToLower(params[4]),
+		versionBits: strings.ToLower(params[5]),
+	}, nil
+}
+
+// key: identifies a share within a job in order to detect duplicates
+func (sh *share) key(extraNonce1 int64) string {
+	return fmt.Sprintf("%08x:%s:%s:%s:%s", extraNonce1, sh.extraNonce2, sh.nTime, sh.nonce, sh.versionBits)
+}
+
+// validateShare: rebuilds the block header for the submitted share and checks it against the connection target. The
+// extranonce is read once, so that a reassignment in the middle can't mix the old and new values
+func (ws *webSocket) validateShare(sh *share) (*shareResult, error) {
+	sub, extraNonce2 := ws.getExtraNonce()
+	if sub == nil {
+		return nil, errShareNotSubscribed
+	}
+	if ws.getWorker(sh.worker) == nil {
+		return nil, errShareUnauthorized
+	}
+	if len(sh.extraNonce2) != int(extraNonce2)*2 || len(sh.nTime) != 8 || len(sh.nonce) != 8 {
+		return nil, errShareMalformed
+	}
+
+	job := ws.svc.jobs.get(sh.jobID)
+	if job == nil {
+		return nil, errShareStale
+	}
+
+	coinbase, header, err := ws.buildBlockHeader(job, sh, sub.extraNonce1, extraNonce2)
+	if err == errShareVersionBits {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errShareMalformed
+	}
+	hash, err := header.Hash()
+	if err != nil {
+		return nil, errShareMalformed
+	}
+
+	if !job.addSubmission(sh.key(sub.extraNonce1)) {
+		return nil, errShareDuplicate
+	}
+	difficulty := ws.vardiff.shareDifficulty(job.createdAt)
+	if bitcoin.HashToBig(hash).Cmp(bitcoin.DifficultyToTarget(difficulty)) > 0 {
+		return nil, errShareLowDifficulty
+	}
+	ws.vardiff.addShare()
+
+	return &shareResult{
+		subscription: sub,
+		job:          job,
+		coinbase:     coinbase,
+		header:       header,
+		hash:         hash,
+		difficulty:   difficulty,
+		isBlock:      job.blockTemplate != nil && bitcoin.HashToBig(hash).Cmp(bitcoin.CompactToTarget(header.Bits)) <= 0,
+	}, nil
+}
+
+func (ws *webSocket) buildBlockHeader(job *Job, sh *share, extraNonce1, extraNonce2 int64) ([]byte, *bitcoin.BlockHeader, error) {
+	coinb1, coinb2, err := ws.coinbase(job, extraNonce2)
+	if err != nil {
+		return nil, nil, err
+	}
+	coinbase, err := hex.DecodeString(coinb1 + fmt.Sprintf("%08x", extraNonce1) + sh.extraNonce2 + coinb2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	branch := make([][]byte, 0, len(job.MerkleBranch))
+	for _, h := range job.MerkleBranch {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, nil, err
+		}
+		branch = append(branch, b)
+	}
+
+	prevHash, err := hex.DecodeString(job.PrevHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	version, err := decodeUint32(job.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	nBits, err := decodeUint32(job.NBits)
+	if err != nil {
+		return nil, nil, err
+	}
+	nTime, err := decodeUint32(sh.nTime)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, err := decodeUint32(sh.nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sh.versionBits != "" {
+		if version, err = ws.rollVersion(version, sh.versionBits); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return coinbase, &bitcoin.BlockHeader{
+		Version:    version,
+		PrevHash:   bitcoin.SwapWords(prevHash),
+		MerkleRoot: bitcoin.MerkleRootFromBranch(bitcoin.DoubleSHA256(coinbase), branch),
+		Time:       nTime,
+		Bits:       nBits,
+		Nonce:      nonce,
+	}, nil
+}
+
+// decodeUint32: decodes the big endian hex values used by Stratum for version, nbits, ntime and nonce
+func decodeUint32(s string) (uint32, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 4 {
+		return 0, fmt.Errorf("invalid length for uint32: %d", len(b))
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// rollVersion: replaces the bits of the job version within the negotiated mask with the ones rolled by the miner
+func (ws *webSocket) rollVersion(version uint32, versionBits string) (uint32, error) {
+	bits, err := decodeUint32(versionBits)
+	if err != nil {
+		return 0, err
+	}
+	if bits&^ws.versionRollingMask != 0 {
+		return 0, errShareVersionBits
+	}
+	return version&^ws.versionRollingMask | bits, nil
+}
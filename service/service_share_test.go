@@ -0,0 +1,137 @@
+package service
+
+import (
+	"encoding/json"
+	"stratum-server/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// genesis block coinbase, split around the 8 bytes following the scriptSig push of nBits
+const (
+	genesisCoinb1      = "01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04"
+	genesisExtraNonce1 = 0xffff001d
+	genesisExtraNonce2 = "01044554"
+	genesisCoinb2      = "68652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000"
+	genesisNTime       = "495fab29"
+	genesisNonce       = "7c2bac1d"
+)
+
+func TestWebSocket_validateShare(t *testing.T) {
+	tests := []struct {
+		name               string
+		subscription       *subscription
+		params             []string
+		jobVersion         string
+		versionRollingMask uint32
+		submitTwice        bool
+		expectedError      error
+	}{
+		{
+			name:          "error without subscription",
+			params:        []string{"worker", "1", genesisExtraNonce2, genesisNTime, genesisNonce},
+			expectedError: errShareNotSubscribed,
+		},
+		{
+			name:          "error with unauthorized worker",
+			subscription:  &subscription{extraNonce1: genesisExtraNonce1},
+			params:        []string{"other", "1", genesisExtraNonce2, genesisNTime, genesisNonce},
+			expectedError: errShareUnauthorized,
+		},
+		{
+			name:          "error with malformed extranonce2",
+			subscription:  &subscription{extraNonce1: genesisExtraNonce1},
+			params:        []string{"worker", "1", "0104", genesisNTime, genesisNonce},
+			expectedError: errShareMalformed,
+		},
+		{
+			name:          "error with unknown job",
+			subscription:  &subscription{extraNonce1: genesisExtraNonce1},
+			params:        []string{"worker", "2", genesisExtraNonce2, genesisNTime, genesisNonce},
+			expectedError: errShareStale,
+		},
+		{
+			name:          "error with low difficulty",
+			subscription:  &subscription{extraNonce1: genesisExtraNonce1},
+			params:        []string{"worker", "1", genesisExtraNonce2, genesisNTime, "7c2bac1e"},
+			expectedError: errShareLowDifficulty,
+		},
+		{
+			name:          "error with duplicate share",
+			subscription:  &subscription{extraNonce1: genesisExtraNonce1},
+			params:        []string{"worker", "1", genesisExtraNonce2, genesisNTime, genesisNonce},
+			submitTwice:   true,
+			expectedError: errShareDuplicate,
+		},
+		{
+			name:               "error with version bits outside of the mask",
+			subscription:       &subscription{extraNonce1: genesisExtraNonce1},
+			params:             []string{"worker", "1", genesisExtraNonce2, genesisNTime, genesisNonce, "e0000000"},
+			jobVersion:         "00002001",
+			versionRollingMask: 0x1fffe000,
+			expectedError:      errShareVersionBits,
+		},
+		{
+			name:          "error with version bits without version rolling",
+			subscription:  &subscription{extraNonce1: genesisExtraNonce1},
+			params:        []string{"worker", "1", genesisExtraNonce2, genesisNTime, genesisNonce, "00002000"},
+			expectedError: errShareVersionBits,
+		},
+		{
+			name:         "no error",
+			subscription: &subscription{extraNonce1: genesisExtraNonce1},
+			params:       []string{"worker", "1", genesisExtraNonce2, genesisNTime, genesisNonce},
+		},
+		{
+			name:               "no error with rolled version",
+			subscription:       &subscription{extraNonce1: genesisExtraNonce1},
+			params:             []string{"worker", "1", genesisExtraNonce2, genesisNTime, genesisNonce, "00000000"},
+			jobVersion:         "00002001",
+			versionRollingMask: 0x1fffe000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(nil, &config.Config{
+				VardiffConfig: config.VardiffConfig{InitialDifficulty: 1},
+			}, nil, nil)
+			if tt.jobVersion == "" {
+				tt.jobVersion = "00000001"
+			}
+			svc.jobs.add(&Job{
+				ID:        "1",
+				PrevHash:  "0000000000000000000000000000000000000000000000000000000000000000",
+				Coinb1:    genesisCoinb1,
+				Coinb2:    genesisCoinb2,
+				Version:   tt.jobVersion,
+				NBits:     "1d00ffff",
+				NTime:     genesisNTime,
+				CleanJobs: true,
+			})
+			ws := &webSocket{
+				svc:          svc,
+				miningConfig: miningConfig{extraNonce2: 4, versionRollingMask: tt.versionRollingMask},
+				vardiff:      newVardiff(svc.vardiffConfig),
+				subscription: tt.subscription,
+				workers: map[string]*worker{
+					"worker": {name: "worker", account: "worker"},
+				},
+			}
+
+			params, _ := json.Marshal(tt.params)
+			sh, err := parseShare(params)
+			assert.NoError(t, err)
+			if tt.submitTwice {
+				_, _ = ws.validateShare(sh)
+			}
+
+			res, err := ws.validateShare(sh)
+			assert.Equal(t, tt.expectedError, err)
+			if err == nil {
+				assert.Equal(t, "1", res.job.ID)
+				assert.Same(t, tt.subscription, res.subscription)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"stratum-server/payout"
+	"stratum-server/repository"
+	"time"
+)
+
+const (
+	shareAccepted = "accepted"
+	shareRejected = "rejected"
+
+	// shares waiting to be persisted, new ones without credits are dropped when it's full
+	shareQueueSize     = 10000
+	shareBatchSize     = 500
+	shareFlushInterval = time.Second
+	// failed batches are retried with an exponential backoff up to this interval
+	shareMaxRetryInterval = time.Minute
+)
+
+type shareRecord struct {
+	extraNonce1  int64
+	account      string
+	worker       string
+	difficulty   float64
+	jobID        string
+	result       string
+	rejectReason string
+	createdAt    time.Time
+	// owed to the account by pay per share schemes, in satoshis
+	credit float64
+	height int64
+}
+
+// recordShare: queues the share to be persisted without ever blocking the connection. When the queue is full, shares
+// without credits are dropped, while credited shares are kept in memory until the writer persists them, so that
+// they're never left unpaid.
+func (s *service) recordShare(record *shareRecord) {
+	select {
+	case s.shareQueue <- record:
+		return
+	default:
+	}
+
+	if record.credit > 0 {
+		s.overflowMu.Lock()
+		s.creditOverflow = append(s.creditOverflow, record)
+		s.overflowMu.Unlock()
+		return
+	}
+	log.Printf("share queue is full, dropping share from extraNonce1 %08x", record.extraNonce1)
+	s.metrics.ShareDropped()
+}
+
+// takeCreditOverflow: returns the credited shares waiting outside of the queue, removing them
+func (s *service) takeCreditOverflow() []*shareRecord {
+	s.overflowMu.Lock()
+	defer s.overflowMu.Unlock()
+	records := s.creditOverflow
+	s.creditOverflow = nil
+	return records
+}
+
+// RunShareWriter: persists the queued shares in batches, either when the batch is full or periodically. A batch that
+// can't be persisted is kept and retried with backoff, leaving the new shares in the queue meanwhile.
+func (s *service) RunShareWriter(ctx context.Context) {
+	ticker := time.NewTicker(shareFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*shareRecord, 0, shareBatchSize)
+	var retryInterval time.Duration
+	var retryAt time.Time
+	flush := func() {
+		batch = append(batch, s.takeCreditOverflow()...)
+		if len(batch) == 0 {
+			return
+		}
+		// the credits are only stored along with their shares
+		if err := s.repository.Transaction(func(tx repository.Repository) error {
+			if err := s.createShares(tx, batch); err != nil {
+				return err
+			}
+			return s.createShareCredits(tx, batch)
+		}); err != nil {
+			retryInterval *= 2
+			if retryInterval < shareFlushInterval {
+				retryInterval = shareFlushInterval
+			} else if retryInterval > shareMaxRetryInterval {
+				retryInterval = shareMaxRetryInterval
+			}
+			retryAt = time.Now().Add(retryInterval)
+			log.Printf("error persisting %d shares, retrying in %s: %v", len(batch), retryInterval, err)
+			return
+		}
+		retryInterval = 0
+		batch = make([]*shareRecord, 0, shareBatchSize)
+	}
+
+	for {
+		// while a batch is waiting to be retried, new shares wait in the queue
+		queue := s.shareQueue
+		if retryInterval > 0 {
+			queue = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			// persist what's already queued before leaving
+			for {
+				select {
+				case record := <-s.shareQueue:
+					batch = append(batch, record)
+				default:
+					flush()
+					if retryInterval > 0 {
+						log.Printf("dropping %d shares that couldn't be persisted before shutting down", len(batch))
+						for range batch {
+							s.metrics.ShareDropped()
+						}
+					}
+					return
+				}
+			}
+		case record := <-queue:
+			batch = append(batch, record)
+			if len(batch) >= shareBatchSize {
+				flush()
+			}
+		case now := <-ticker.C:
+			if retryInterval == 0 || !now.Before(retryAt) {
+				flush()
+			}
+		}
+	}
+}
+
+func (s *service) createShares(repo repository.Repository, records []*shareRecord) error {
+	sqlStatement := fmt.Sprintf(`
+	INSERT INTO %s.%s (extra_nonce_1, account, worker, difficulty, job_id, result, reject_reason, created_at)`,
+		s.sharesTable.Schema, s.sharesTable.Name)
+
+	rows := make([][]interface{}, 0, len(records))
+	for _, r := range records {
+		rows = append(rows, []interface{}{
+			r.extraNonce1,
+			r.account,
+			r.worker,
+			r.difficulty,
+			r.jobID,
+			r.result,
+			r.rejectReason,
+			r.createdAt,
+		})
+	}
+
+	if err := repo.BatchInsert(repository.BatchInsertRequest{
+		Query: sqlStatement,
+		Rows:  rows,
+	}); err != nil {
+		log.Printf("error creating shares: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// createShareCredits: persists the credits granted to the accepted shares
+func (s *service) createShareCredits(repo repository.Repository, records []*shareRecord) error {
+	if s.payoutScheme == nil {
+		return nil
+	}
+
+	var credits []payout.Credit
+	for _, r := range records {
+		if r.credit <= 0 {
+			continue
+		}
+		credits = append(credits, payout.Credit{
+			Account: r.account,
+			Height:  r.height,
+			Amount:  r.credit,
+		})
+	}
+
+	return s.payoutScheme.CreateCredits(repo, credits)
+}
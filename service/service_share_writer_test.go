@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"stratum-server/config"
+	"stratum-server/metrics"
+	"stratum-server/repository"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// droppedSharesMetrics counts the dropped shares, discarding the rest of the metrics
+type droppedSharesMetrics struct {
+	metrics.Metrics
+	dropped int32
+}
+
+func (m *droppedSharesMetrics) ShareDropped() {
+	atomic.AddInt32(&m.dropped, 1)
+}
+
+func TestService_RunShareWriter(t *testing.T) {
+	createdAt := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	record := &shareRecord{
+		extraNonce1: 1,
+		account:     "alice",
+		worker:      "alice.rig1",
+		difficulty:  512,
+		jobID:       "1",
+		result:      shareAccepted,
+		createdAt:   createdAt,
+	}
+
+	tests := []struct {
+		name          string
+		records       int
+		shutdown      bool
+		expectedSizes []int
+	}{
+		{
+			name:          "flushes a full batch right away",
+			records:       shareBatchSize + 1,
+			expectedSizes: []int{shareBatchSize, 1},
+		},
+		{
+			name:          "flushes a partial batch on the interval",
+			records:       3,
+			expectedSizes: []int{3},
+		},
+		{
+			name:          "flushes the queued shares on shutdown",
+			records:       3,
+			shutdown:      true,
+			expectedSizes: []int{3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches := make(chan [][]interface{}, 10)
+			repo := &RepositoryMock{
+				BatchInsertFunc: func(input repository.BatchInsertRequest) error {
+					batches <- input.Rows
+					return nil
+				},
+			}
+			repo.TransactionFunc = func(fn func(tx repository.Repository) error) error {
+				return fn(repo)
+			}
+			svc := NewService(repo, &config.Config{}, nil, nil)
+			for i := 0; i < tt.records; i++ {
+				svc.recordShare(record)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			if tt.shutdown {
+				cancel()
+			} else {
+				defer cancel()
+			}
+			go func() {
+				svc.RunShareWriter(ctx)
+				close(done)
+			}()
+			if tt.shutdown {
+				<-done
+			}
+
+			for _, size := range tt.expectedSizes {
+				select {
+				case rows := <-batches:
+					assert.Len(t, rows, size)
+					assert.Equal(t, []interface{}{int64(1), "alice", "alice.rig1", 512.0, "1", shareAccepted, "", createdAt}, rows[0])
+				case <-time.After(3 * shareFlushInterval):
+					t.Fatalf("batch of %d shares not flushed", size)
+				}
+			}
+			assert.Len(t, batches, 0)
+		})
+	}
+}
+
+func TestService_recordShare(t *testing.T) {
+	m := &droppedSharesMetrics{Metrics: metrics.NewNoop()}
+	svc := NewService(nil, &config.Config{}, nil, m)
+
+	for i := 0; i < shareQueueSize; i++ {
+		svc.recordShare(&shareRecord{extraNonce1: 1})
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&m.dropped))
+
+	// the connection is never blocked, the share is dropped instead
+	svc.recordShare(&shareRecord{extraNonce1: 1})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&m.dropped))
+	assert.Len(t, svc.shareQueue, shareQueueSize)
+}
+
+func TestService_RunShareWriter_credits(t *testing.T) {
+	tests := []struct {
+		name            string
+		sharesErr       error
+		expectedInserts []string
+	}{
+		{
+			name:            "shares and credits are stored together",
+			expectedInserts: []string{"shares", "credits"},
+		},
+		{
+			name:            "credits are skipped when the shares can't be stored",
+			sharesErr:       fmt.Errorf("connection refused"),
+			expectedInserts: []string{"shares"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inserts []string
+			var transactions int
+			repo := &RepositoryMock{
+				BatchInsertFunc: func(input repository.BatchInsertRequest) error {
+					if strings.Contains(input.Query, "credits") {
+						inserts = append(inserts, "credits")
+						return nil
+					}
+					inserts = append(inserts, "shares")
+					return tt.sharesErr
+				},
+			}
+			repo.TransactionFunc = func(fn func(tx repository.Repository) error) error {
+				transactions++
+				return fn(repo)
+			}
+			svc := NewService(repo, &config.Config{
+				PoolConfig:   config.PoolConfig{Mode: config.MiningModePool},
+				PayoutConfig: config.PayoutConfig{Scheme: config.PayoutSchemePPS},
+				PostgreSQLConfig: config.PostgreSQLConfig{
+					SharesTable:  config.PostgreSQLTableConfig{Schema: "public", Name: "shares"},
+					CreditsTable: config.PostgreSQLTableConfig{Schema: "public", Name: "credits"},
+				},
+			}, nil, nil)
+			svc.recordShare(&shareRecord{extraNonce1: 1, account: "alice", result: shareAccepted, credit: 1250})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			svc.RunShareWriter(ctx)
+
+			assert.Equal(t, 1, transactions)
+			assert.Equal(t, tt.expectedInserts, inserts)
+		})
+	}
+}
+
+func TestService_recordShare_credited(t *testing.T) {
+	m := &droppedSharesMetrics{Metrics: metrics.NewNoop()}
+	svc := NewService(nil, &config.Config{}, nil, m)
+	for i := 0; i < shareQueueSize; i++ {
+		svc.recordShare(&shareRecord{extraNonce1: 1})
+	}
+
+	// credited shares are kept apart instead of being dropped, without blocking the connection
+	queued := make(chan struct{})
+	go func() {
+		svc.recordShare(&shareRecord{extraNonce1: 2, credit: 1250})
+		close(queued)
+	}()
+	select {
+	case <-queued:
+	case <-time.After(time.Second):
+		t.Fatal("credited share blocked by the full queue")
+	}
+	assert.Len(t, svc.shareQueue, shareQueueSize)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&m.dropped))
+	assert.Equal(t, []*shareRecord{{extraNonce1: 2, credit: 1250}}, svc.takeCreditOverflow())
+	assert.Empty(t, svc.takeCreditOverflow())
+}
+
+func TestService_RunShareWriter_retry(t *testing.T) {
+	var attempts int32
+	persisted := make(chan [][]interface{}, 1)
+	repo := &RepositoryMock{
+		BatchInsertFunc: func(input repository.BatchInsertRequest) error {
+			if strings.Contains(input.Query, "credits") {
+				return nil
+			}
+			// the database is down for the first attempt
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				return fmt.Errorf("connection refused")
+			}
+			persisted <- input.Rows
+			return nil
+		},
+	}
+	repo.TransactionFunc = func(fn func(tx repository.Repository) error) error {
+		return fn(repo)
+	}
+	m := &droppedSharesMetrics{Metrics: metrics.NewNoop()}
+	svc := NewService(repo, &config.Config{
+		PoolConfig:   config.PoolConfig{Mode: config.MiningModePool},
+		PayoutConfig: config.PayoutConfig{Scheme: config.PayoutSchemePPS},
+		PostgreSQLConfig: config.PostgreSQLConfig{
+			SharesTable:  config.PostgreSQLTableConfig{Schema: "public", Name: "shares"},
+			CreditsTable: config.PostgreSQLTableConfig{Schema: "public", Name: "credits"},
+		},
+	}, nil, m)
+	svc.recordShare(&shareRecord{extraNonce1: 1, account: "alice", result: shareAccepted, credit: 1250})
+	// the second share overflows the queue while the first batch is waiting to be retried
+	svc.creditOverflow = []*shareRecord{{extraNonce1: 2, account: "bob", result: shareAccepted, credit: 1250}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go svc.RunShareWriter(ctx)
+
+	select {
+	case rows := <-persisted:
+		assert.Len(t, rows, 2)
+	case <-time.After(5 * shareFlushInterval):
+		t.Fatal("failed batch not retried")
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&m.dropped))
+}
@@ -47,8 +47,8 @@ func (s *service) getExistingSubscription(subscriber string, extraNonce1 int64)
 
 func (s *service) createSubscription(subscriber string) (*subscription, error) {
 	sqlStatement := fmt.Sprintf(`
-	INSERT INTO %s.%s (extra_nonce_2, set_difficulty, notify, subscriber)
-	VALUES ($1, $2, $3, $4)
+	INSERT INTO %s.%s (extra_nonce_2, set_difficulty, notify, subscriber, instance_id)
+	VALUES ($1, $2, $3, $4, $5)
 	RETURNING extra_nonce_1, extra_nonce_2, set_difficulty, notify, subscriber, created_at, active_session`, s.subscriptionsTable.Schema, s.subscriptionsTable.Name)
 
 	sub := &subscription{}
@@ -59,6 +59,7 @@ func (s *service) createSubscription(subscriber string) (*subscription, error) {
 			uuid.NewString(),
 			uuid.NewString(),
 			subscriber,
+			s.instanceConfig.ID,
 		},
 	}, &sub.extraNonce1, &sub.extraNonce2, &sub.setDifficulty, &sub.notify, &sub.subscriber, &sub.createdAt, &sub.activeSession); err != nil {
 		log.Printf("error creating subscription: %v", err)
@@ -68,12 +69,78 @@ func (s *service) createSubscription(subscriber string) (*subscription, error) {
 	return sub, nil
 }
 
+// activateSubscription: resumes an inactive subscription in the instance, returns false if it was resumed meanwhile
+func (s *service) activateSubscription(subscription *subscription) (bool, error) {
+	sqlStatement := fmt.Sprintf(`
+	UPDATE %s.%s
+	SET active_session = true, instance_id = $1
+	WHERE extra_nonce_1 = $2
+	AND active_session = false
+	RETURNING active_session
+	`, s.subscriptionsTable.Schema, s.subscriptionsTable.Name)
+
+	if err := s.repository.Update(repository.UpdateRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			s.instanceConfig.ID,
+			subscription.extraNonce1,
+		},
+	}, &subscription.activeSession); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		log.Printf("error activating subscription: %v", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// reassignSubscription: moves the subscription held by the instance to a new extraNonce1 and extraNonce2 size, returns
+// nil if the extraNonce1 is already used by another subscription. The unique constraint covers the subscriptions
+// created or reassigned concurrently, which the NOT EXISTS guard can't see
+func (s *service) reassignSubscription(sub *subscription, extraNonce1, extraNonce2 int64) (*subscription, error) {
+	sqlStatement := fmt.Sprintf(`
+	UPDATE %s.%s
+	SET extra_nonce_1 = $1, extra_nonce_2 = $2
+	WHERE extra_nonce_1 = $3
+	AND instance_id = $4
+	AND NOT EXISTS (
+		SELECT 1 FROM %s.%s
+		WHERE extra_nonce_1 = $1
+		AND extra_nonce_1 <> $3
+	)
+	RETURNING extra_nonce_1, extra_nonce_2
+	`, s.subscriptionsTable.Schema, s.subscriptionsTable.Name, s.subscriptionsTable.Schema, s.subscriptionsTable.Name)
+
+	reassigned := *sub
+	if err := s.repository.Update(repository.UpdateRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			extraNonce1,
+			extraNonce2,
+			sub.extraNonce1,
+			s.instanceConfig.ID,
+		},
+	}, &reassigned.extraNonce1, &reassigned.extraNonce2); err != nil {
+		if err == sql.ErrNoRows || repository.IsUniqueViolation(err) {
+			return nil, nil
+		}
+		log.Printf("error reassigning subscription: %v", err)
+		return nil, err
+	}
+
+	return &reassigned, nil
+}
+
 func (s *service) inactiveSubscription(subscription *subscription) {
 
+	// the subscription could already be resumed in another instance
 	sqlStatement := fmt.Sprintf(`
 	UPDATE %s.%s
 	SET active_session = $1
 	WHERE extra_nonce_1 = $2
+	AND instance_id = $3
 	RETURNING active_session
 	`, s.subscriptionsTable.Schema, s.subscriptionsTable.Name)
 	var activeSession bool
@@ -83,8 +150,37 @@ func (s *service) inactiveSubscription(subscription *subscription) {
 		Args: []interface{}{
 			false,
 			subscription.extraNonce1,
+			s.instanceConfig.ID,
 		},
-	}, &activeSession); err != nil {
+	}, &activeSession); err != nil && err != sql.ErrNoRows {
 		log.Printf("error inactivating subscription: %v", err)
 	}
 }
+
+// inactiveSubscriptions: marks every subscription held by the instance as inactive at once, instead of one statement
+// per connection
+func (s *service) inactiveSubscriptions() error {
+	sqlStatement := fmt.Sprintf(`
+	WITH inactivated AS (
+		UPDATE %s.%s
+		SET active_session = false
+		WHERE active_session = true
+		AND instance_id = $1
+		RETURNING extra_nonce_1
+	)
+	SELECT COUNT(*) FROM inactivated`, s.subscriptionsTable.Schema, s.subscriptionsTable.Name)
+
+	var count int64
+	if err := s.repository.Update(repository.UpdateRequest{
+		Query: sqlStatement,
+		Args: []interface{}{
+			s.instanceConfig.ID,
+		},
+	}, &count); err != nil {
+		log.Printf("error inactivating subscriptions: %v", err)
+		return err
+	}
+
+	log.Printf("%d subscriptions marked as inactive", count)
+	return nil
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"stratum-server/repository"
+	"time"
+)
+
+// newSubscriptionsRepository: creates every subscription with the given extraNonce1 and fixed ids, so that the
+// mining.subscribe responses can be compared as they are
+func newSubscriptionsRepository(extraNonce1 int64) *RepositoryMock {
+	return &RepositoryMock{
+		InsertFunc: func(input repository.InsertRequest, destinationArgs ...interface{}) error {
+			*destinationArgs[0].(*int64) = extraNonce1
+			*destinationArgs[1].(*int64) = input.Args[0].(int64)
+			*destinationArgs[2].(*string) = "b4b6693b-72ad-4d8a-8a0f-1bf6d0c8f4a1"
+			*destinationArgs[3].(*string) = "3c0b9f3e-5a53-4d7e-9f4c-2a1d8f1e6b7c"
+			*destinationArgs[4].(*string) = input.Args[3].(string)
+			*destinationArgs[5].(*time.Time) = time.Now()
+			*destinationArgs[6].(*bool) = true
+			return nil
+		},
+	}
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"net"
+)
+
+func (s *service) RunTCPConnection(_ context.Context, conn net.Conn) {
+	if s.isDraining() {
+		conn.Close()
+		return
+	}
+
+	webSocket := NewWebSocket(newTCPTransport(conn), s)
+
+	// routine to read messages
+	go webSocket.Read()
+	// routine to write messages
+	go webSocket.Write()
+	// routine to graceful shutdown
+	go webSocket.Shutdown()
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"stratum-server/bitcoin"
+	"stratum-server/payout"
+	"stratum-server/template"
+)
+
+var (
+	// OP_TRUE, only used to get the rest of the job parts in solo mode
+	soloPlaceholderScript = []byte{0x51}
+)
+
+// RunTemplates: publishes a new job for every template received from the template source
+func (s *service) RunTemplates(ctx context.Context) {
+	templates := make(chan *template.Template)
+	go s.templateSource.Run(ctx, templates)
+
+	var previous *template.Template
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-templates:
+			job, err := s.newJob(t)
+			if err != nil {
+				log.Printf("error building job from template at height %d: %v", t.Height, err)
+				continue
+			}
+			// a new block invalidates any previous work
+			job.CleanJobs = previous == nil || !bytes.Equal(previous.PrevHash, t.PrevHash)
+			previous = t
+
+			s.PublishJob(job)
+		}
+	}
+}
+
+func (s *service) newJob(t *template.Template) (*Job, error) {
+	// in solo mode the coinbase is built for every connection, paying to its own address
+	payoutScript := s.payoutScript
+	if s.isSoloMode() {
+		payoutScript = soloPlaceholderScript
+	}
+
+	parts, err := t.JobParts(payoutScript, []byte(s.poolConfig.CoinbaseTag), extraNonce1Size+int(s.GetExtraNonce2()))
+	if err != nil {
+		return nil, err
+	}
+	if s.isSoloMode() {
+		parts.Coinb1, parts.Coinb2 = "", ""
+	}
+
+	return &Job{
+		PrevHash:      parts.PrevHash,
+		Coinb1:        parts.Coinb1,
+		Coinb2:        parts.Coinb2,
+		MerkleBranch:  parts.MerkleBranch,
+		Version:       parts.Version,
+		NBits:         parts.NBits,
+		NTime:         parts.NTime,
+		blockTemplate: t,
+	}, nil
+}
+
+// newRound: describes the block mined with the template, used to credit the shares
+func newRound(t *template.Template) payout.Round {
+	return payout.Round{
+		Height:            t.Height,
+		NetworkDifficulty: bitcoin.TargetToDifficulty(bitcoin.CompactToTarget(t.Bits)),
+		Subsidy:           t.CoinbaseValue - t.Fees,
+		Fees:              t.Fees,
+	}
+}
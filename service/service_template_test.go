@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"stratum-server/config"
+	"stratum-server/template"
+	"stratum-server/template/templatetest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sequenceTemplateSource sends the given templates in order, as if they were fetched from the node
+type sequenceTemplateSource struct {
+	nopTemplateSource
+	templates []*template.Template
+}
+
+func (s *sequenceTemplateSource) Run(ctx context.Context, templates chan<- *template.Template) {
+	for _, t := range s.templates {
+		select {
+		case templates <- t:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func TestService_RunTemplates(t *testing.T) {
+	tests := []struct {
+		name string
+		// heights of the templates, the previous block hash only changes along with the height
+		heights           []int64
+		expectedCleanJobs []bool
+	}{
+		{
+			name:              "first template",
+			heights:           []int64{100},
+			expectedCleanJobs: []bool{true},
+		},
+		{
+			name:              "refresh on the same tip",
+			heights:           []int64{100, 100},
+			expectedCleanJobs: []bool{true, false},
+		},
+		{
+			name:              "new tip after a refresh",
+			heights:           []int64{100, 100, 101},
+			expectedCleanJobs: []bool{true, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &sequenceTemplateSource{}
+			for i, height := range tt.heights {
+				res := templatetest.NewGetBlockTemplateResult(height)
+				// refreshes on the same tip only change the transactions, and so the coinbase value
+				res.CoinbaseValue += int64(i)
+				tmpl, err := template.NewTemplate(res)
+				assert.NoError(t, err)
+				source.templates = append(source.templates, tmpl)
+			}
+			svc := NewService(nil, &config.Config{
+				PoolConfig: config.PoolConfig{
+					Mode:         config.MiningModePool,
+					PayoutScript: "76a914000000000000000000000000000000000000000088ac",
+				},
+			}, source, nil)
+			ws := newHubSession(1)
+			ws.svc = svc
+			ws.inboundMsg = make(chan []byte, len(tt.heights))
+			svc.hub.register(ws)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go svc.RunTemplates(ctx)
+
+			var cleanJobs []bool
+			for range tt.expectedCleanJobs {
+				select {
+				case raw := <-ws.inboundMsg:
+					var msg struct {
+						Method string        `json:"method"`
+						Params []interface{} `json:"params"`
+					}
+					assert.NoError(t, json.Unmarshal(raw, &msg))
+					assert.Equal(t, miningNotifyKey, msg.Method)
+					cleanJobs = append(cleanJobs, msg.Params[8].(bool))
+				case <-time.After(time.Second):
+					t.Fatal("job not published")
+				}
+			}
+			assert.Equal(t, tt.expectedCleanJobs, cleanJobs)
+			assert.Equal(t, tt.expectedCleanJobs[len(tt.expectedCleanJobs)-1], svc.jobs.currentJob().CleanJobs)
+		})
+	}
+}
@@ -6,11 +6,17 @@ import (
 )
 
 const (
+	extraNonce1Size          = 4
 	defaultExtraNonce2 int64 = 4
 )
 
 func (s *service) RunWebsocketConnection(_ context.Context, conn *websocket.Conn) {
-	webSocket := NewWebSocket(conn, s)
+	if s.isDraining() {
+		conn.Close()
+		return
+	}
+
+	webSocket := NewWebSocket(newWSTransport(conn), s)
 
 	// routine to read messages
 	go webSocket.Read()
@@ -0,0 +1,20 @@
+package service
+
+import "net"
+
+const (
+	wsTransportName  = "ws"
+	tcpTransportName = "tcp"
+)
+
+// transport abstracts the connection used by a stratum session, so that the same
+// request dispatch can be used for WebSocket and plain TCP miners.
+type transport interface {
+	ReadMessage() ([]byte, error)
+	WriteMessage(msg []byte) error
+	Ping() error
+	// Close: gracefully closes the connection
+	Close() error
+	RemoteAddr() net.Addr
+	Name() string
+}
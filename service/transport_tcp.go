@@ -0,0 +1,80 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"net"
+	"time"
+)
+
+const (
+	// max length of a newline-delimited JSON message
+	maxTCPMessageSize = 16 * 1024
+	tcpWriteTimeout   = 10 * time.Second
+	// there are no pings on plain TCP, so connections without any message for this long are considered dead
+	tcpReadTimeout = 5 * pingPeriod
+)
+
+type tcpTransport struct {
+	conn        net.Conn
+	scanner     *bufio.Scanner
+	readTimeout time.Duration
+}
+
+func newTCPTransport(conn net.Conn) *tcpTransport {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 1024), maxTCPMessageSize)
+
+	return &tcpTransport{
+		conn:        conn,
+		scanner:     scanner,
+		readTimeout: tcpReadTimeout,
+	}
+}
+
+// ReadMessage: waits for the next non blank line, renewing the read deadline on every call
+func (t *tcpTransport) ReadMessage() ([]byte, error) {
+	// it only fails once the connection is closed, which is reported after the lines already buffered
+	_ = t.conn.SetReadDeadline(time.Now().Add(t.readTimeout))
+	for t.scanner.Scan() {
+		line := bytes.TrimSpace(t.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		// the scanner reuses its buffer on every call
+		return append([]byte{}, line...), nil
+	}
+	if err := t.scanner.Err(); err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			log.Printf("no message from %s in %s, closing idle connection", t.conn.RemoteAddr(), t.readTimeout)
+		}
+		return nil, err
+	}
+	return nil, net.ErrClosed
+}
+
+func (t *tcpTransport) WriteMessage(msg []byte) error {
+	if err := t.conn.SetWriteDeadline(time.Now().Add(tcpWriteTimeout)); err != nil {
+		return err
+	}
+	_, err := t.conn.Write(append(msg, '\n'))
+	return err
+}
+
+// Ping: there are no control frames on plain TCP, miners are expected to keep submitting shares
+func (t *tcpTransport) Ping() error {
+	return nil
+}
+
+func (t *tcpTransport) Close() error {
+	return t.conn.Close()
+}
+
+func (t *tcpTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+func (t *tcpTransport) Name() string {
+	return tcpTransportName
+}
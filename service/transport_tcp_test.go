@@ -0,0 +1,144 @@
+package service
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"stratum-server/config"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTCPTransport_ReadMessage(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		expectedMessages []string
+		expectedError    error
+	}{
+		{
+			name:             "newline framing",
+			input:            `{"id":1}` + "\n" + `{"id":2}` + "\n",
+			expectedMessages: []string{`{"id":1}`, `{"id":2}`},
+			expectedError:    net.ErrClosed,
+		},
+		{
+			name:             "blank lines are skipped",
+			input:            "\n" + `{"id":1}` + "\n\n  \n" + `{"id":2}` + "\n",
+			expectedMessages: []string{`{"id":1}`, `{"id":2}`},
+			expectedError:    net.ErrClosed,
+		},
+		{
+			name:             "CRLF line endings",
+			input:            `{"id":1}` + "\r\n" + `{"id":2}` + "\r\n",
+			expectedMessages: []string{`{"id":1}`, `{"id":2}`},
+			expectedError:    net.ErrClosed,
+		},
+		{
+			name:             "last line without newline",
+			input:            `{"id":1}`,
+			expectedMessages: []string{`{"id":1}`},
+			expectedError:    net.ErrClosed,
+		},
+		{
+			name:             "line at the max size",
+			input:            strings.Repeat("a", maxTCPMessageSize-1) + "\n",
+			expectedMessages: []string{strings.Repeat("a", maxTCPMessageSize-1)},
+			expectedError:    net.ErrClosed,
+		},
+		{
+			name:             "error with line over the max size",
+			input:            `{"id":1}` + "\n" + strings.Repeat("a", maxTCPMessageSize+1) + "\n",
+			expectedMessages: []string{`{"id":1}`},
+			expectedError:    bufio.ErrTooLong,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			go func() {
+				_, _ = client.Write([]byte(tt.input))
+				client.Close()
+			}()
+
+			tr := newTCPTransport(server)
+			var messages []string
+			for {
+				msg, err := tr.ReadMessage()
+				if err != nil {
+					assert.Equal(t, tt.expectedError, err)
+					break
+				}
+				messages = append(messages, string(msg))
+			}
+			assert.Equal(t, tt.expectedMessages, messages)
+		})
+	}
+}
+
+func TestWebSocket_Read_oversizedLine(t *testing.T) {
+	svc := NewService(nil, &config.Config{}, nil, nil)
+	server, client := net.Pipe()
+	defer client.Close()
+	ws := NewWebSocket(newTCPTransport(server), svc).(*webSocket)
+	go ws.Read()
+	go ws.Write()
+	go ws.Shutdown()
+
+	// the miner keeps writing, it's only unblocked when the connection is closed
+	written := make(chan error, 1)
+	go func() {
+		_, err := client.Write([]byte(strings.Repeat("a", 2*maxTCPMessageSize)))
+		written <- err
+	}()
+
+	select {
+	case <-ws.done:
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed after an oversized line")
+	}
+	select {
+	case err := <-written:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("miner still blocked writing")
+	}
+}
+
+func TestWebSocket_Read_idle(t *testing.T) {
+	const readTimeout = 100 * time.Millisecond
+
+	svc := NewService(nil, &config.Config{}, nil, nil)
+	server, client := net.Pipe()
+	defer client.Close()
+	tr := newTCPTransport(server)
+	tr.readTimeout = readTimeout
+	ws := NewWebSocket(tr, svc).(*webSocket)
+	go ws.Read()
+	go ws.Write()
+	go ws.Shutdown()
+	go io.Copy(io.Discard, client)
+
+	// every message renews the deadline
+	for i := 0; i < 5; i++ {
+		_, err := client.Write([]byte(`{"id":1,"method":"mining.extranonce.subscribe","params":[]}` + "\n"))
+		assert.NoError(t, err)
+		time.Sleep(readTimeout / 2)
+	}
+	select {
+	case <-ws.done:
+		t.Fatal("active connection closed")
+	default:
+	}
+
+	// the half-open connection is closed once it stops sending messages
+	select {
+	case <-ws.done:
+	case <-time.After(10 * readTimeout):
+		t.Fatal("idle connection not closed")
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"log"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type wsTransport struct {
+	conn *websocket.Conn
+}
+
+func newWSTransport(conn *websocket.Conn) *wsTransport {
+	return &wsTransport{
+		conn: conn,
+	}
+}
+
+func (t *wsTransport) ReadMessage() ([]byte, error) {
+	_, message, err := t.conn.ReadMessage()
+	if err != nil && websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNoStatusReceived) {
+		log.Print("unexpected close, shutting down ws")
+	}
+	return message, err
+}
+
+func (t *wsTransport) WriteMessage(msg []byte) error {
+	return t.conn.WriteMessage(websocket.TextMessage, msg)
+}
+
+func (t *wsTransport) Ping() error {
+	return t.conn.WriteMessage(websocket.PingMessage, nil)
+}
+
+func (t *wsTransport) Close() error {
+	t.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "closing"), time.Now().Add(time.Second*5))
+	return t.conn.Close()
+}
+
+func (t *wsTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+func (t *wsTransport) Name() string {
+	return wsTransportName
+}
@@ -1,12 +1,14 @@
 package service
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
+	"stratum-server/bitcoin"
+	"stratum-server/metrics"
+	"sync"
 	"time"
-
-	"github.com/gorilla/websocket"
 )
 
 const (
@@ -15,6 +17,14 @@ const (
 
 	miningAuthorizeMethod = "mining.authorize"
 	miningSubscribeMethod = "mining.subscribe"
+	miningSubmitMethod    = "mining.submit"
+	miningConfigureMethod = "mining.configure"
+	unknownMethod         = "unknown"
+
+	miningExtraNonceSubscribeMethod = "mining.extranonce.subscribe"
+
+	// max requests in a single batch
+	maxBatchSize = 100
 )
 
 var (
@@ -44,20 +54,48 @@ var (
 		Message: "Parse error",
 	}
 
+	// Stratum specific errors.
+	errStratumOther = &rpcError{
+		Code:    20,
+		Message: "Other/Unknown",
+	}
+	errStratumJobNotFound = &rpcError{
+		Code:    21,
+		Message: "Job not found",
+	}
+	errStratumDuplicateShare = &rpcError{
+		Code:    22,
+		Message: "Duplicate share",
+	}
+	errStratumLowDifficulty = &rpcError{
+		Code:    23,
+		Message: "Low difficulty share",
+	}
+	errStratumUnauthorized = &rpcError{
+		Code:    24,
+		Message: "Unauthorized worker",
+	}
+	errStratumNotSubscribed = &rpcError{
+		Code:    25,
+		Message: "Not subscribed",
+	}
+
 	errInboundMsgDecode = fmt.Errorf("failed to encode incoming message")
 	errInboundMsgReq    = fmt.Errorf("invalid rpc request")
 )
 
+// rpcRequest keeps the id and params as raw JSON: the id is echoed verbatim, and the params are parsed by every method.
 type rpcRequest struct {
-	ID     int64    `json:"id"`
-	Method string   `json:"method"`
-	Params []string `json:"params,omitempty"`
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
 }
 
+// rpcResponse is never written as is, it's encoded in the configured protocol dialect.
 type rpcResponse struct {
-	ID     int64       `json:"id,omitempty"`
-	Result interface{} `json:"result,omitempty"`
-	Error  *rpcError   `json:"error,omitempty"`
+	ID     json.RawMessage
+	Result interface{}
+	Error  *rpcError
 }
 
 type rpcError struct {
@@ -77,19 +115,46 @@ type Websocket interface {
 
 type miningConfig struct {
 	extraNonce2 int64
+	// version bits agreed through mining.configure, 0 when version rolling isn't negotiated
+	versionRollingMask uint32
 }
 
 type webSocket struct {
-	svc        *service
-	conn       *websocket.Conn
-	close      chan struct{}
+	svc   *service
+	conn  transport
+	close chan struct{}
+	// makes CloseConn safe to be called more than once
+	closeOnce sync.Once
+	// closed once the connection is shutdown
+	done       chan struct{}
 	inboundMsg chan []byte
+	// protects inboundMsg from being written after it's closed
+	mu     sync.Mutex
+	closed bool
 	miningConfig
+	vardiff *vardiff
+	// replaced from other routines when its extranonce is reassigned, only accessed through getSubscription and
+	// setSubscription
 	subscription *subscription
+	// the miner accepts mining.set_extranonce, so its extraNonce1 can be reassigned mid-session
+	extraNonceSubscribed bool
+	// protects the session state read from other routines
+	sessionMu sync.RWMutex
+	// workers authorized through mining.authorize, by username
+	workers map[string]*worker
+	// first payout address authorized in the connection
+	payoutAddress *bitcoin.Address
+
+	connectedAt    time.Time
+	sharesAccepted uint64
+	sharesRejected uint64
+
+	// notifications waiting for the current message to be answered, only used by the Read routine
+	pendingNotifications []func()
 }
 
 func NewWebSocket(
-	conn *websocket.Conn,
+	conn transport,
 	svc *service,
 ) Websocket {
 	ws := &webSocket{
@@ -97,10 +162,15 @@ func NewWebSocket(
 		conn:       conn,
 		inboundMsg: make(chan []byte, 256),
 		close:      make(chan struct{}),
+		done:       make(chan struct{}),
 		miningConfig: miningConfig{
 			extraNonce2: svc.GetExtraNonce2(),
 		},
+		vardiff:     newVardiff(svc.vardiffConfig),
+		workers:     make(map[string]*worker),
+		connectedAt: time.Now(),
 	}
+	svc.metrics.ConnectionOpened(conn.Name())
 
 	return ws
 }
@@ -113,11 +183,8 @@ func (ws *webSocket) Read() {
 		ws.CloseConn()
 	}()
 	for {
-		_, message, err := ws.conn.ReadMessage()
+		message, err := ws.conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNoStatusReceived) {
-				log.Print("unexpected close, shutting down ws")
-			}
 			break
 		}
 		ws.handleMessage(message)
@@ -141,12 +208,12 @@ func (ws *webSocket) Write() {
 				return
 			}
 
-			if err := ws.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Printf("failed to write msg in websocket: %v", err)
+			if err := ws.conn.WriteMessage(message); err != nil {
+				log.Printf("failed to write msg in %s conn: %v", ws.conn.Name(), err)
 				return
 			}
 		case <-ticker.C:
-			if err := ws.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := ws.conn.Ping(); err != nil {
 				log.Printf("failed to send Ping msg: %v", err)
 				return
 			}
@@ -157,16 +224,22 @@ func (ws *webSocket) Write() {
 func (ws *webSocket) Shutdown() {
 	<-ws.close
 
-	ws.conn.WriteControl(websocket.CloseMessage,
-		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "closing"), time.Now().Add(time.Second*5))
 	ws.conn.Close()
+	ws.svc.hub.unregister(ws)
+
+	ws.mu.Lock()
+	ws.closed = true
 	close(ws.inboundMsg)
+	ws.mu.Unlock()
 
-	if ws.hasActiveSubscription() {
-		ws.svc.inactiveSubscription(ws.subscription)
+	// when draining, every subscription is marked as inactive at once
+	if ws.hasActiveSubscription() && !ws.svc.isDraining() {
+		ws.svc.inactiveSubscription(ws.getSubscription())
 	}
+	ws.svc.metrics.ConnectionClosed(ws.conn.Name())
+	close(ws.done)
 
-	log.Print("websocket conn ended")
+	log.Printf("%s conn ended", ws.conn.Name())
 }
 
 func (ws *webSocket) WriteMsg(i interface{}) {
@@ -174,69 +247,180 @@ func (ws *webSocket) WriteMsg(i interface{}) {
 	if err != nil {
 		return
 	}
-	ws.inboundMsg <- raw
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closed {
+		return
+	}
+	select {
+	case ws.inboundMsg <- raw:
+	default:
+		// dropping a response or a job would leave the miner out of sync, so the slow connection is closed instead
+		log.Print("channel inboundMsg is full, closing connection")
+		ws.CloseConn()
+	}
 }
 
 func (ws *webSocket) CloseConn() {
-	ws.close <- struct{}{}
+	ws.closeOnce.Do(func() {
+		close(ws.close)
+	})
 }
 
 func (ws *webSocket) hasActiveSubscription() bool {
-	return ws.subscription != nil
+	return ws.getSubscription() != nil
+}
+
+func (ws *webSocket) addWorker(w *worker) {
+	ws.sessionMu.Lock()
+	defer ws.sessionMu.Unlock()
+	ws.workers[w.name] = w
+}
+
+// getWorker: returns the authorized worker with the given username, nil if it's not authorized
+func (ws *webSocket) getWorker(name string) *worker {
+	ws.sessionMu.RLock()
+	defer ws.sessionMu.RUnlock()
+	return ws.workers[name]
 }
 
 func (ws *webSocket) handleMessage(msg []byte) {
+	if isBatch(msg) {
+		ws.handleBatch(msg)
+	} else {
+		ws.WriteMsg(ws.svc.encodeResponse(ws.handleRequest(msg)))
+	}
+	// notifications triggered by the requests are sent once they're answered
+	ws.runAfterResponse()
+}
+
+// handleBatch: handles every request of a JSON-RPC batch in order, writing all the responses at once
+func (ws *webSocket) handleBatch(msg []byte) {
+	var reqs []json.RawMessage
+	if err := json.Unmarshal(msg, &reqs); err != nil {
+		ws.WriteMsg(ws.svc.encodeResponse(ws.buildErrorResponse(nil, errInboundMsgDecode)))
+		return
+	}
+	if len(reqs) == 0 || len(reqs) > maxBatchSize {
+		ws.WriteMsg(ws.svc.encodeResponse(ws.buildErrorResponse(nil, errInboundMsgReq)))
+		return
+	}
+
+	responses := make([]interface{}, 0, len(reqs))
+	for _, req := range reqs {
+		responses = append(responses, ws.svc.encodeResponse(ws.handleRequest(req)))
+	}
+	ws.WriteMsg(responses)
+}
+
+// handleRequest: decodes and dispatches a single request, returning its response
+func (ws *webSocket) handleRequest(msg []byte) *rpcResponse {
 	req, err := ws.decodeMessage(msg)
 	if err != nil {
-		ws.sendError(err)
-		return
+		// the id is kept when the message is a valid JSON object
+		var id json.RawMessage
+		if req != nil {
+			id = req.ID
+		}
+		return ws.buildErrorResponse(id, err)
 	}
 
+	method := req.Method
+	var res *rpcResponse
 	switch req.Method {
 	case miningAuthorizeMethod:
-		ws.handleMiningAuthorize(req)
+		res = ws.handleMiningAuthorize(req)
 	case miningSubscribeMethod:
-		ws.handleMiningSubscribe(req)
+		res = ws.handleMiningSubscribe(req)
+	case miningSubmitMethod:
+		res = ws.handleMiningSubmit(req)
+	case miningConfigureMethod:
+		res = ws.handleMiningConfigure(req)
+	case miningExtraNonceSubscribeMethod:
+		res = ws.handleMiningExtraNonceSubscribe(req)
 	default:
-		ws.WriteMsg(&rpcResponse{ID: req.ID, Error: errRPCMethodNotFound})
-		return
+		// unknown methods aren't used as labels, since they're chosen by the client
+		method = unknownMethod
+		res = &rpcResponse{ID: req.ID, Error: errRPCMethodNotFound}
 	}
+
+	ws.recordRequest(method, res)
+	return res
+}
+
+// afterResponse: defers fn until the response, or the whole batch, is written, so that notifications never precede it
+func (ws *webSocket) afterResponse(fn func()) {
+	ws.pendingNotifications = append(ws.pendingNotifications, fn)
+}
+
+func (ws *webSocket) runAfterResponse() {
+	for _, fn := range ws.pendingNotifications {
+		fn()
+	}
+	ws.pendingNotifications = nil
+}
+
+// isBatch: JSON-RPC batches are arrays of requests
+func isBatch(msg []byte) bool {
+	trimmed := bytes.TrimLeft(msg, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
 }
 
 func (ws *webSocket) decodeMessage(msg []byte) (*rpcRequest, error) {
 	req := &rpcRequest{}
 	err := json.Unmarshal(msg, req)
-	if err != nil {
+	_, isTypeErr := err.(*json.UnmarshalTypeError)
+	if err != nil && !isTypeErr {
 		return nil, errInboundMsgDecode
 	}
 
-	if req.ID == 0 || req.Method == "" {
-		return nil, errInboundMsgReq
+	if !isValidRequestID(req.ID) {
+		// an id that can't be echoed is dropped
+		return &rpcRequest{}, errInboundMsgReq
+	}
+	// valid JSON, but not a request object: the rest of the fields are still decoded, so the id is echoed
+	if isTypeErr || req.Method == "" {
+		return req, errInboundMsgReq
 	}
 
 	return req, nil
 }
 
-func (ws *webSocket) sendError(err error) {
-	res := ws.buildErrorResponse(err)
-	ws.WriteMsg(res)
+// isValidRequestID: ids must be present, and either a number, a string or null
+func isValidRequestID(id json.RawMessage) bool {
+	if len(id) == 0 {
+		return false
+	}
+	switch id[0] {
+	case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	default:
+		return false
+	}
+}
+
+// recordRequest: counts the handled request by method and result
+func (ws *webSocket) recordRequest(method string, res *rpcResponse) {
+	if res.Error != nil {
+		ws.svc.metrics.RequestHandled(method, metrics.ResultError, res.Error.Code)
+	} else {
+		ws.svc.metrics.RequestHandled(method, metrics.ResultOK, 0)
+	}
 }
 
-func (ws *webSocket) buildErrorResponse(err error) *rpcResponse {
+func (ws *webSocket) buildErrorResponse(id json.RawMessage, err error) *rpcResponse {
 	var res *rpcResponse
 	switch err {
-	case nil:
-		log.Print("no error")
-		res = nil
 	case errInboundMsgDecode:
 		log.Print("error decoding JSON-RPC message")
-		res = &rpcResponse{Error: errRRCParse}
+		res = &rpcResponse{ID: id, Error: errRRCParse}
 	case errInboundMsgReq:
 		log.Print("invalid JSON-RPC message")
-		res = &rpcResponse{Error: errRPCInvalidReq}
+		res = &rpcResponse{ID: id, Error: errRPCInvalidReq}
 	default:
 		log.Print("input pipe unknown error")
-		res = &rpcResponse{Error: errRPCInternal}
+		res = &rpcResponse{ID: id, Error: errRPCInternal}
 	}
 
 	return res
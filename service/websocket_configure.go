@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math/bits"
+	"strconv"
+)
+
+const (
+	versionRollingExtension     = "version-rolling"
+	versionRollingMaskOption    = "version-rolling.mask"
+	versionRollingMinBitsOption = "version-rolling.min-bit-count"
+	miningSetVersionMaskMethod  = "mining.set_version_mask"
+)
+
+// handleMiningConfigure: negotiates the protocol extensions (BIP 310). Only version-rolling and subscribe-extranonce
+// are supported, the rest of the extensions are answered with false so that the miner doesn't rely on them.
+func (ws *webSocket) handleMiningConfigure(req *rpcRequest) *rpcResponse {
+	log.Print("[mining.configure] request")
+
+	params, err := parseConfigureParams(req.Params)
+	if err != nil {
+		return &rpcResponse{ID: req.ID, Error: errRPCInvalidParams}
+	}
+
+	result := make(map[string]interface{}, len(params.extensions))
+	for _, extension := range params.extensions {
+		switch extension {
+		case versionRollingExtension:
+			mask, err := ws.negotiateVersionRolling(params)
+			if err != nil {
+				return &rpcResponse{ID: req.ID, Error: errRPCInvalidParams}
+			}
+			result[versionRollingExtension] = mask != 0
+			if mask != 0 {
+				result[versionRollingMaskOption] = fmt.Sprintf("%08x", mask)
+			}
+		case subscribeExtraNonceExtension:
+			// same as mining.extranonce.subscribe
+			ws.subscribeExtraNonce()
+			result[subscribeExtraNonceExtension] = true
+		default:
+			result[extension] = false
+		}
+	}
+
+	return &rpcResponse{ID: req.ID, Result: result}
+}
+
+// negotiateVersionRolling: the agreed mask is the intersection of the pool and miner masks, and it's stored in the
+// connection so that the rolled shares can be validated
+func (ws *webSocket) negotiateVersionRolling(params *configureParams) (uint32, error) {
+	// every bit can be rolled when the miner doesn't send its mask
+	minerMask := uint64(0xffffffff)
+	var hexMask string
+	ok, err := params.option(versionRollingMaskOption, &hexMask)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		if minerMask, err = strconv.ParseUint(hexMask, 16, 32); err != nil {
+			return 0, errParamsMalformed
+		}
+	}
+	var minBits int
+	if _, err := params.option(versionRollingMinBitsOption, &minBits); err != nil {
+		return 0, err
+	}
+
+	mask := ws.svc.poolConfig.VersionRollingMask & uint32(minerMask)
+	if bits.OnesCount32(mask) < minBits {
+		log.Printf("version rolling mask %08x has less than the %d bits required by the miner", mask, minBits)
+		mask = 0
+	}
+
+	ws.versionRollingMask = mask
+	if mask != 0 {
+		ws.afterResponse(func() {
+			ws.sendNotification(newSetVersionMaskNotification(mask))
+		})
+	}
+	return mask, nil
+}
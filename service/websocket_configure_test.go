@@ -0,0 +1,87 @@
+package service
+
+import (
+	"stratum-server/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocket_handleMiningConfigure(t *testing.T) {
+	tests := []struct {
+		name                 string
+		poolMask             uint32
+		msg                  string
+		expectedResponse     string
+		expectedNotification string
+		expectedMask         uint32
+		expectedExtraNonce   bool
+	}{
+		{
+			name:                 "version rolling with the intersection of both masks",
+			poolMask:             0x1fffe000,
+			msg:                  `{"id":1,"method":"mining.configure","params":[["version-rolling","minimum-difficulty"],{"version-rolling.mask":"ffffffff","version-rolling.min-bit-count":2,"minimum-difficulty.value":2048}]}`,
+			expectedResponse:     `{"id":1,"result":{"minimum-difficulty":false,"version-rolling":true,"version-rolling.mask":"1fffe000"},"error":null}`,
+			expectedNotification: `{"id":null,"method":"mining.set_version_mask","params":["1fffe000"]}`,
+			expectedMask:         0x1fffe000,
+		},
+		{
+			name:                 "version rolling without miner mask",
+			poolMask:             0x1fffe000,
+			msg:                  `{"id":2,"method":"mining.configure","params":[["version-rolling"],{}]}`,
+			expectedResponse:     `{"id":2,"result":{"version-rolling":true,"version-rolling.mask":"1fffe000"},"error":null}`,
+			expectedNotification: `{"id":null,"method":"mining.set_version_mask","params":["1fffe000"]}`,
+			expectedMask:         0x1fffe000,
+		},
+		{
+			name:                 "version rolling with a partial miner mask",
+			poolMask:             0x1fffe000,
+			msg:                  `{"id":3,"method":"mining.configure","params":[["version-rolling"],{"version-rolling.mask":"00fff000"}]}`,
+			expectedResponse:     `{"id":3,"result":{"version-rolling":true,"version-rolling.mask":"00ffe000"},"error":null}`,
+			expectedNotification: `{"id":null,"method":"mining.set_version_mask","params":["00ffe000"]}`,
+			expectedMask:         0x00ffe000,
+		},
+		{
+			name:             "version rolling with less bits than required",
+			poolMask:         0x00006000,
+			msg:              `{"id":4,"method":"mining.configure","params":[["version-rolling"],{"version-rolling.mask":"1fffe000","version-rolling.min-bit-count":16}]}`,
+			expectedResponse: `{"id":4,"result":{"version-rolling":false},"error":null}`,
+		},
+		{
+			name:               "version rolling disabled",
+			msg:                `{"id":5,"method":"mining.configure","params":[["version-rolling","subscribe-extranonce"],{"version-rolling.mask":"1fffe000"}]}`,
+			expectedResponse:   `{"id":5,"result":{"subscribe-extranonce":true,"version-rolling":false},"error":null}`,
+			expectedExtraNonce: true,
+		},
+		{
+			name:             "error with invalid mask",
+			poolMask:         0x1fffe000,
+			msg:              `{"id":6,"method":"mining.configure","params":[["version-rolling"],{"version-rolling.mask":"xyz"}]}`,
+			expectedResponse: `{"id":6,"result":null,"error":[-32602,"Invalid params",null]}`,
+		},
+		{
+			name:             "error without extensions",
+			poolMask:         0x1fffe000,
+			msg:              `{"id":7,"method":"mining.configure","params":[]}`,
+			expectedResponse: `{"id":7,"result":null,"error":[-32602,"Invalid params",null]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(nil, &config.Config{PoolConfig: config.PoolConfig{VersionRollingMask: tt.poolMask}}, nil, nil)
+			ws := &webSocket{
+				svc:        svc,
+				inboundMsg: make(chan []byte, 2),
+			}
+
+			ws.handleMessage([]byte(tt.msg))
+			assert.Equal(t, tt.expectedResponse, string(<-ws.inboundMsg))
+			if tt.expectedNotification != "" {
+				assert.Equal(t, tt.expectedNotification, string(<-ws.inboundMsg))
+			}
+			assertNoPendingMessages(t, ws)
+			assert.Equal(t, tt.expectedMask, ws.versionRollingMask)
+			assert.Equal(t, tt.expectedExtraNonce, ws.isExtraNonceSubscribed())
+		})
+	}
+}
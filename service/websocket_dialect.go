@@ -0,0 +1,70 @@
+package service
+
+import (
+	"encoding/json"
+	"stratum-server/config"
+)
+
+const (
+	jsonRPCVersion = "2.0"
+)
+
+// stratumResponse: classic Stratum V1 response, where id, result and error are always present and errors are encoded
+// as [code, message, traceback]
+type stratumResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result"`
+	Error  []interface{}   `json:"error"`
+}
+
+// jsonRPC2Result: JSON-RPC 2.0 success response, the result is always present even when it's false or null
+type jsonRPC2Result struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+}
+
+// jsonRPC2Error: JSON-RPC 2.0 error response, the id is null when it couldn't be read from the request
+type jsonRPC2Error struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *rpcError       `json:"error"`
+}
+
+// stratumNotification: classic Stratum V1 notification, with a null id
+type stratumNotification struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params []interface{}   `json:"params"`
+}
+
+// jsonRPC2Notification: JSON-RPC 2.0 notification, which has no id
+type jsonRPC2Notification struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// encodeResponse: builds the response envelope of the configured protocol dialect. A nil id is encoded as null.
+func (s *service) encodeResponse(res *rpcResponse) interface{} {
+	if s.protocolDialect == config.ProtocolDialectJSONRPC2 {
+		if res.Error != nil {
+			return &jsonRPC2Error{JSONRPC: jsonRPCVersion, ID: res.ID, Error: res.Error}
+		}
+		return &jsonRPC2Result{JSONRPC: jsonRPCVersion, ID: res.ID, Result: res.Result}
+	}
+
+	stratum := &stratumResponse{ID: res.ID, Result: res.Result}
+	if res.Error != nil {
+		stratum.Error = []interface{}{res.Error.Code, res.Error.Message, res.Error.Data}
+	}
+	return stratum
+}
+
+// encodeNotification: builds the notification envelope of the configured protocol dialect
+func (s *service) encodeNotification(n *rpcNotification) interface{} {
+	if s.protocolDialect == config.ProtocolDialectJSONRPC2 {
+		return &jsonRPC2Notification{JSONRPC: jsonRPCVersion, Method: n.Method, Params: n.Params}
+	}
+	return &stratumNotification{Method: n.Method, Params: n.Params}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"stratum-server/config"
+	"stratum-server/repository"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_encodeResponse(t *testing.T) {
+	tests := []struct {
+		name             string
+		dialect          string
+		res              *rpcResponse
+		expectedResponse string
+	}{
+		{
+			name:             "stratum result",
+			dialect:          config.ProtocolDialectStratum,
+			res:              &rpcResponse{ID: json.RawMessage(`1`), Result: true},
+			expectedResponse: `{"id":1,"result":true,"error":null}`,
+		},
+		{
+			name:             "stratum false result",
+			dialect:          config.ProtocolDialectStratum,
+			res:              &rpcResponse{ID: json.RawMessage(`"a"`), Result: false},
+			expectedResponse: `{"id":"a","result":false,"error":null}`,
+		},
+		{
+			name:             "stratum error",
+			dialect:          config.ProtocolDialectStratum,
+			res:              &rpcResponse{ID: json.RawMessage(`2`), Error: errStratumJobNotFound},
+			expectedResponse: `{"id":2,"result":null,"error":[21,"Job not found",null]}`,
+		},
+		{
+			name:             "stratum error without id",
+			dialect:          config.ProtocolDialectStratum,
+			res:              &rpcResponse{Error: errRRCParse},
+			expectedResponse: `{"id":null,"result":null,"error":[-32700,"Parse error",null]}`,
+		},
+		{
+			name:             "stratum by default",
+			res:              &rpcResponse{ID: json.RawMessage(`1`), Result: true},
+			expectedResponse: `{"id":1,"result":true,"error":null}`,
+		},
+		{
+			name:             "jsonrpc2 result",
+			dialect:          config.ProtocolDialectJSONRPC2,
+			res:              &rpcResponse{ID: json.RawMessage(`1`), Result: false},
+			expectedResponse: `{"jsonrpc":"2.0","id":1,"result":false}`,
+		},
+		{
+			name:             "jsonrpc2 error",
+			dialect:          config.ProtocolDialectJSONRPC2,
+			res:              &rpcResponse{ID: json.RawMessage(`2`), Error: errStratumJobNotFound},
+			expectedResponse: `{"jsonrpc":"2.0","id":2,"error":{"code":21,"message":"Job not found"}}`,
+		},
+		{
+			name:             "jsonrpc2 error without id",
+			dialect:          config.ProtocolDialectJSONRPC2,
+			res:              &rpcResponse{Error: errRRCParse},
+			expectedResponse: `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(nil, &config.Config{ProtocolDialect: tt.dialect}, nil, nil)
+
+			raw, err := json.Marshal(svc.encodeResponse(tt.res))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedResponse, string(raw))
+		})
+	}
+}
+
+// TestWebSocket_conformance: replays the miner sessions in testdata/conformance, checking every message written
+func TestWebSocket_conformance(t *testing.T) {
+	transcripts, err := filepath.Glob(filepath.Join("testdata", "conformance", "*.txt"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, transcripts)
+
+	for _, transcript := range transcripts {
+		t.Run(filepath.Base(transcript), func(t *testing.T) {
+			ws := newConformanceSession()
+
+			f, err := os.Open(transcript)
+			assert.NoError(t, err)
+			defer f.Close()
+
+			scanner := bufio.NewScanner(f)
+			scanner.Buffer(make([]byte, 0, 4096), 64*1024)
+			for scanner.Scan() {
+				line := scanner.Text()
+				switch {
+				case strings.HasPrefix(line, "> "):
+					assertNoPendingMessages(t, ws)
+					ws.handleMessage([]byte(strings.TrimPrefix(line, "> ")))
+				case strings.HasPrefix(line, "< "):
+					select {
+					case msg := <-ws.inboundMsg:
+						assert.JSONEq(t, strings.TrimPrefix(line, "< "), string(msg))
+					default:
+						t.Fatalf("missing message: %s", line)
+					}
+				}
+			}
+			assert.NoError(t, scanner.Err())
+			assertNoPendingMessages(t, ws)
+		})
+	}
+}
+
+func newConformanceSession() *webSocket {
+	repo := newSubscriptionsRepository(genesisExtraNonce1)
+	// previous subscriptions are never found
+	repo.QueryFunc = func(input repository.QueryRequest, destinationArgs ...interface{}) error {
+		return sql.ErrNoRows
+	}
+	svc := NewService(repo, &config.Config{
+		AuthMode:        config.AuthModeNone,
+		ProtocolDialect: config.ProtocolDialectStratum,
+		VardiffConfig:   config.VardiffConfig{InitialDifficulty: 1},
+	}, nil, nil)
+	svc.jobs.add(&Job{
+		ID:        "1",
+		PrevHash:  "0000000000000000000000000000000000000000000000000000000000000000",
+		Coinb1:    genesisCoinb1,
+		Coinb2:    genesisCoinb2,
+		Version:   "00000001",
+		NBits:     "1d00ffff",
+		NTime:     genesisNTime,
+		CleanJobs: true,
+	})
+
+	return &webSocket{
+		svc:          svc,
+		inboundMsg:   make(chan []byte, 16),
+		miningConfig: miningConfig{extraNonce2: svc.GetExtraNonce2()},
+		vardiff:      newVardiff(svc.vardiffConfig),
+		workers:      make(map[string]*worker),
+	}
+}
+
+func assertNoPendingMessages(t *testing.T, ws *webSocket) {
+	select {
+	case msg := <-ws.inboundMsg:
+		t.Fatalf("unexpected message: %s", msg)
+	default:
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"log"
+)
+
+const (
+	subscribeExtraNonceExtension = "subscribe-extranonce"
+	miningSetExtraNonceMethod    = "mining.set_extranonce"
+)
+
+// handleMiningExtraNonceSubscribe: the miner, usually a proxy, accepts a new extraNonce1 at any time through
+// mining.set_extranonce. The method takes no params.
+func (ws *webSocket) handleMiningExtraNonceSubscribe(req *rpcRequest) *rpcResponse {
+	log.Print("[mining.extranonce.subscribe] request")
+
+	if _, err := decodeParams(req.Params); err != nil {
+		return &rpcResponse{ID: req.ID, Error: errRPCInvalidParams}
+	}
+
+	ws.subscribeExtraNonce()
+	return &rpcResponse{ID: req.ID, Result: true}
+}
+
+func (ws *webSocket) subscribeExtraNonce() {
+	ws.sessionMu.Lock()
+	defer ws.sessionMu.Unlock()
+	ws.extraNonceSubscribed = true
+}
+
+func (ws *webSocket) isExtraNonceSubscribed() bool {
+	ws.sessionMu.RLock()
+	defer ws.sessionMu.RUnlock()
+	return ws.extraNonceSubscribed
+}
+
+// getSubscription: the subscription is replaced from other routines when its extraNonce1 is reassigned
+func (ws *webSocket) getSubscription() *subscription {
+	ws.sessionMu.RLock()
+	defer ws.sessionMu.RUnlock()
+	return ws.subscription
+}
+
+// getExtraNonce: snapshot of the subscription along with the extraNonce2 size, both changed at once on reassignments
+func (ws *webSocket) getExtraNonce() (*subscription, int64) {
+	ws.sessionMu.RLock()
+	defer ws.sessionMu.RUnlock()
+	return ws.subscription, ws.extraNonce2
+}
+
+func (ws *webSocket) getExtraNonce2() int64 {
+	ws.sessionMu.RLock()
+	defer ws.sessionMu.RUnlock()
+	return ws.extraNonce2
+}
+
+func (ws *webSocket) setSubscription(sub *subscription) {
+	ws.sessionMu.Lock()
+	defer ws.sessionMu.Unlock()
+	ws.subscription = sub
+}
+
+// setExtraNonce: replaces the subscription along with the extraNonce2 size, so that the snapshot taken by getExtraNonce
+// never sees one without the other
+func (ws *webSocket) setExtraNonce(sub *subscription, extraNonce2 int64) {
+	ws.sessionMu.Lock()
+	defer ws.sessionMu.Unlock()
+	ws.subscription = sub
+	ws.extraNonce2 = extraNonce2
+}
+
+// sendExtraNonce: the new extranonce only applies to the next job, so the current one is sent right after it
+func (ws *webSocket) sendExtraNonce(sub *subscription) {
+	ws.sendNotification(newSetExtraNonceNotification(sub.extraNonce1, sub.extraNonce2))
+	ws.sendCurrentJob()
+}
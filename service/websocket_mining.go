@@ -1,10 +1,15 @@
 package service
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"log"
+	"stratum-server/metrics"
+	"stratum-server/payout"
 	"strconv"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -12,81 +17,210 @@ const (
 	miningNotifyKey        = "mining.notify"
 )
 
-func (ws *webSocket) handleMiningAuthorize(req *rpcRequest) {
+func (ws *webSocket) handleMiningAuthorize(req *rpcRequest) *rpcResponse {
 	log.Print("[mining.authorize] request")
 
 	var response *rpcResponse
-	if ws.isValidMiningAuthorize(req) {
-		response = &rpcResponse{ID: req.ID, Result: true}
+	if params, err := parseAuthorizeParams(req.Params); err == nil {
+		response = ws.handleWorkerAuthorization(req, params)
 	} else {
-		response = &rpcResponse{Error: errRPCInvalidParams}
+		response = &rpcResponse{ID: req.ID, Error: errRPCInvalidParams}
 	}
 
-	ws.WriteMsg(response)
+	return response
 }
 
-func (ws *webSocket) handleMiningSubscribe(req *rpcRequest) {
+func (ws *webSocket) handleWorkerAuthorization(req *rpcRequest, params *authorizeParams) *rpcResponse {
+	w, err := ws.svc.authorize(params.username, params.password)
+	if addressErr, ok := err.(*invalidAddressError); ok {
+		return &rpcResponse{ID: req.ID, Error: &rpcError{Code: errStratumUnauthorized.Code, Message: addressErr.Error()}}
+	}
+
+	switch err {
+	case nil:
+		set, err := ws.setPayoutAddress(w.address)
+		if err != nil {
+			log.Printf("worker %s not authorized: %v", params.username, err)
+			return &rpcResponse{ID: req.ID, Error: &rpcError{Code: errStratumUnauthorized.Code, Message: err.Error()}}
+		}
+		ws.addWorker(w)
+		// in solo mode, jobs can't be built until the connection has a payout address
+		if set && ws.svc.isSoloMode() && ws.hasActiveSubscription() {
+			ws.afterResponse(ws.sendCurrentJob)
+		}
+		return &rpcResponse{ID: req.ID, Result: true}
+	case errAuthInvalidCredentials:
+		log.Printf("worker %s not authorized", params.username)
+		return &rpcResponse{ID: req.ID, Error: errStratumUnauthorized}
+	default:
+		return &rpcResponse{ID: req.ID, Error: errRPCInternal}
+	}
+}
+
+func (ws *webSocket) handleMiningSubscribe(req *rpcRequest) *rpcResponse {
 	log.Print("[mining.subscribe] request")
 
 	var response *rpcResponse
-	if ws.subscription != nil {
+	params, err := parseSubscribeParams(req.Params)
+	switch {
+	case err != nil:
+		response = &rpcResponse{ID: req.ID, Error: errRPCInvalidParams}
+	case ws.hasActiveSubscription():
 		log.Print("already subscribed!")
-		response = &rpcResponse{Error: errRPCInvalidParams}
+		response = &rpcResponse{ID: req.ID, Error: errRPCInvalidParams}
+	case params.isRequestingExistingSubscription():
+		response = ws.handleExistingSubscription(req.ID, params)
+	default:
+		response = ws.handleNewSubscription(req.ID, params)
+	}
+
+	if response.Error == nil {
+		ws.svc.hub.register(ws)
+		ws.afterResponse(func() {
+			ws.sendDifficulty(ws.vardiff.currentDifficulty())
+			ws.sendCurrentJob()
+		})
+	}
+	return response
+}
+
+func (ws *webSocket) handleMiningSubmit(req *rpcRequest) *rpcResponse {
+	log.Print("[mining.submit] request")
+
+	var response *rpcResponse
+	var res *shareResult
+	sh, err := parseShare(req.Params)
+	if err == nil {
+		res, err = ws.validateShare(sh)
+	}
+	if err != nil {
+		log.Printf("share rejected: %v", err)
+		atomic.AddUint64(&ws.sharesRejected, 1)
+		ws.svc.metrics.ShareSubmitted(metrics.ResultRejected, ws.vardiff.currentDifficulty())
+		response = &rpcResponse{ID: req.ID, Error: ws.buildShareError(err)}
 	} else {
-		if ws.isRequestingExistingSubscription(req) {
-			response = ws.handleExistingSubscription(req)
-		} else {
-			response = ws.handleNewSubscription(req)
+		atomic.AddUint64(&ws.sharesAccepted, 1)
+		ws.svc.metrics.ShareSubmitted(metrics.ResultAccepted, res.difficulty)
+		ws.svc.hashrate.addShare(res.difficulty)
+		response = &rpcResponse{ID: req.ID, Result: true}
+		if res.isBlock {
+			go ws.svc.submitBlock(res.subscription, sh, res)
 		}
 	}
 
-	ws.WriteMsg(response)
+	ws.recordShare(req.Params, res, err)
+	return response
 }
 
-func (ws *webSocket) handleExistingSubscription(req *rpcRequest) *rpcResponse {
-	subscriber := req.Params[0]
-	extraNonce1, err := strconv.ParseInt(req.Params[1], 16, 64)
+// recordShare: persists the result of the submitted share, as long as the connection is subscribed
+func (ws *webSocket) recordShare(raw json.RawMessage, res *shareResult, err error) {
+	if !ws.hasActiveSubscription() {
+		return
+	}
+
+	record := &shareRecord{
+		extraNonce1: ws.getSubscription().extraNonce1,
+		createdAt:   time.Now(),
+	}
+	if res != nil {
+		record.extraNonce1 = res.subscription.extraNonce1
+	}
+	// malformed shares are recorded with whatever could be parsed
+	if params, _, parseErr := stringParams(raw, 2); parseErr == nil {
+		record.worker = params[0]
+		record.account, _ = parseWorkerName(params[0])
+		record.jobID = params[1]
+	}
+	if err != nil {
+		record.result = shareRejected
+		record.rejectReason = err.Error()
+		record.difficulty = ws.vardiff.currentDifficulty()
+	} else {
+		record.result = shareAccepted
+		record.difficulty = res.difficulty
+		if ws.svc.payoutScheme != nil && res.job.blockTemplate != nil {
+			record.height = res.job.blockTemplate.Height
+			record.credit = ws.svc.payoutScheme.ShareCredit(payout.Share{
+				Account:    record.account,
+				Difficulty: res.difficulty,
+			}, newRound(res.job.blockTemplate))
+		}
+	}
+
+	ws.svc.recordShare(record)
+}
+
+func (ws *webSocket) buildShareError(err error) *rpcError {
+	switch err {
+	case errShareNotSubscribed:
+		return errStratumNotSubscribed
+	case errShareUnauthorized:
+		return errStratumUnauthorized
+	case errShareMalformed:
+		return errRPCInvalidParams
+	case errShareStale:
+		return errStratumJobNotFound
+	case errShareDuplicate:
+		return errStratumDuplicateShare
+	case errShareLowDifficulty:
+		return errStratumLowDifficulty
+	case errShareVersionBits:
+		return &rpcError{Code: errStratumOther.Code, Message: err.Error()}
+	default:
+		return errStratumOther
+	}
+}
+
+func (ws *webSocket) handleExistingSubscription(requestID json.RawMessage, params *subscribeParams) *rpcResponse {
+	extraNonce1, err := strconv.ParseUint(params.extraNonce1, 16, 32)
 	if err != nil {
 		log.Printf("error converting hexadecimal extraNonce1 to integer value: %v", err)
-		return &rpcResponse{Error: errRPCInvalidParams}
+		return &rpcResponse{ID: requestID, Error: errRPCInvalidParams}
 	}
 
-	subscription, err := ws.svc.getExistingSubscription(subscriber, extraNonce1)
+	subscription, err := ws.svc.getExistingSubscription(params.subscriber, int64(extraNonce1))
 	if err != nil {
-		return &rpcResponse{Error: errRPCInternal}
+		return &rpcResponse{ID: requestID, Error: errRPCInternal}
 	}
 	if subscription == nil {
-		return &rpcResponse{Error: errRPCInvalidParams}
+		return &rpcResponse{ID: requestID, Error: errRPCInvalidParams}
 	}
 	if subscription.activeSession {
 		log.Printf("subscription from subscriber: %s and extraNonce1: %d is already active", subscription.subscriber, extraNonce1)
-		return &rpcResponse{Error: errRPCInvalidParams}
+		return &rpcResponse{ID: requestID, Error: errRPCInvalidParams}
 	}
 
-	ws.subscription = subscription
-	return ws.buildSubscriptionRPCResponse(req.ID, subscription)
+	activated, err := ws.svc.activateSubscription(subscription)
+	if err != nil {
+		return &rpcResponse{ID: requestID, Error: errRPCInternal}
+	}
+	if !activated {
+		log.Printf("subscription from subscriber: %s and extraNonce1: %d was resumed by another connection", subscription.subscriber, extraNonce1)
+		return &rpcResponse{ID: requestID, Error: errRPCInvalidParams}
+	}
+
+	ws.setSubscription(subscription)
+	return ws.buildSubscriptionRPCResponse(requestID, subscription)
 }
 
-func (ws *webSocket) handleNewSubscription(req *rpcRequest) *rpcResponse {
-	var subscriber string
-	if len(req.Params) > 0 && req.Params[0] != "" {
-		subscriber = req.Params[0]
-	} else {
+func (ws *webSocket) handleNewSubscription(requestID json.RawMessage, params *subscribeParams) *rpcResponse {
+	subscriber := params.subscriber
+	if subscriber == "" {
 		// if no param is received, random uuid is assigned
 		subscriber = uuid.NewString()
 	}
 
 	subscription, err := ws.svc.createSubscription(subscriber)
 	if err != nil {
-		return &rpcResponse{Error: errRPCInternal}
+		return &rpcResponse{ID: requestID, Error: errRPCInternal}
 	}
 
-	ws.subscription = subscription
-	return ws.buildSubscriptionRPCResponse(req.ID, subscription)
+	ws.setSubscription(subscription)
+	return ws.buildSubscriptionRPCResponse(requestID, subscription)
 }
 
-func (ws *webSocket) buildSubscriptionRPCResponse(requestId int64, subscription *subscription) *rpcResponse {
-	return &rpcResponse{ID: requestId, Result: []interface{}{
+func (ws *webSocket) buildSubscriptionRPCResponse(requestID json.RawMessage, subscription *subscription) *rpcResponse {
+	return &rpcResponse{ID: requestID, Result: []interface{}{
 		[]interface{}{
 			[]string{miningSetDifficultyKey, subscription.setDifficulty},
 			[]string{miningNotifyKey, subscription.notify},
@@ -96,16 +230,24 @@ func (ws *webSocket) buildSubscriptionRPCResponse(requestId int64, subscription
 	}}
 }
 
-func (ws *webSocket) isValidMiningAuthorize(req *rpcRequest) bool {
-	if len(req.Params) != 2 {
-		return false
-	}
-	if req.Params[0] == "" {
-		return false
+func (ws *webSocket) sendDifficulty(difficulty float64) {
+	ws.sendNotification(newSetDifficultyNotification(difficulty))
+}
+
+func (ws *webSocket) sendJob(job *Job, cleanJobs bool) {
+	coinb1, coinb2, err := ws.coinbase(job, ws.getExtraNonce2())
+	if err != nil {
+		log.Printf("skipping job %s: %v", job.ID, err)
+		return
 	}
-	return true
+	ws.sendNotification(newNotifyNotification(job.notifyParams(coinb1, coinb2, cleanJobs)))
 }
 
-func (ws *webSocket) isRequestingExistingSubscription(req *rpcRequest) bool {
-	return len(req.Params) == 2 && req.Params[0] != "" && req.Params[1] != ""
+// sendCurrentJob: sends the latest job to a freshly subscribed connection, forcing it to drop any previous work
+func (ws *webSocket) sendCurrentJob() {
+	job := ws.svc.jobs.currentJob()
+	if job == nil {
+		return
+	}
+	ws.sendJob(job, true)
 }
@@ -0,0 +1,54 @@
+package service
+
+import "fmt"
+
+const (
+	clientReconnectMethod   = "client.reconnect"
+	clientShowMessageMethod = "client.show_message"
+)
+
+// rpcNotification is a message initiated by the server, which is never answered by the miner. Like responses, it's
+// encoded in the configured protocol dialect.
+type rpcNotification struct {
+	Method string
+	Params []interface{}
+}
+
+func newSetDifficultyNotification(difficulty float64) *rpcNotification {
+	return &rpcNotification{Method: miningSetDifficultyKey, Params: []interface{}{difficulty}}
+}
+
+func newNotifyNotification(params []interface{}) *rpcNotification {
+	return &rpcNotification{Method: miningNotifyKey, Params: params}
+}
+
+// newReconnectNotification: asks the miner to reconnect to the given host and port, or to the same host when empty
+func newReconnectNotification(host string, port int64) *rpcNotification {
+	params := []interface{}{}
+	if host != "" {
+		params = append(params, host)
+		if port != 0 {
+			params = append(params, port)
+		}
+	}
+	return &rpcNotification{Method: clientReconnectMethod, Params: params}
+}
+
+// newSetVersionMaskNotification: the mask is hex encoded, as in mining.configure
+func newSetVersionMaskNotification(mask uint32) *rpcNotification {
+	return &rpcNotification{Method: miningSetVersionMaskMethod, Params: []interface{}{fmt.Sprintf("%08x", mask)}}
+}
+
+// newSetExtraNonceNotification: the extraNonce1 is hex encoded, as in mining.subscribe
+func newSetExtraNonceNotification(extraNonce1, extraNonce2 int64) *rpcNotification {
+	return &rpcNotification{Method: miningSetExtraNonceMethod, Params: []interface{}{fmt.Sprintf("%08x", extraNonce1), extraNonce2}}
+}
+
+// newShowMessageNotification: the message is shown to the miner operator
+func newShowMessageNotification(message string) *rpcNotification {
+	return &rpcNotification{Method: clientShowMessageMethod, Params: []interface{}{message}}
+}
+
+func (ws *webSocket) sendNotification(n *rpcNotification) {
+	ws.WriteMsg(ws.svc.encodeNotification(n))
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+var (
+	errParamsMalformed = fmt.Errorf("malformed params")
+)
+
+// authorizeParams: params of mining.authorize, the password is optional
+type authorizeParams struct {
+	username string
+	password string
+}
+
+// subscribeParams: params of mining.subscribe, both optional. The extraNonce1 is hex encoded.
+type subscribeParams struct {
+	subscriber  string
+	extraNonce1 string
+}
+
+// configureParams: params of mining.configure, the extensions requested by the miner and their options
+type configureParams struct {
+	extensions []string
+	options    map[string]json.RawMessage
+}
+
+// decodeParams: splits the positional params, missing or null params are the same as no params
+func decodeParams(raw json.RawMessage) ([]json.RawMessage, error) {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	var params []json.RawMessage
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, errParamsMalformed
+	}
+	return params, nil
+}
+
+// stringParam: strings are used as is, numbers keep their literal text and null is an empty string
+func stringParam(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", errParamsMalformed
+	}
+
+	switch raw[0] {
+	case 'n':
+		return "", nil
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", errParamsMalformed
+		}
+		return s, nil
+	default:
+		var n json.Number
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return "", errParamsMalformed
+		}
+		return n.String(), nil
+	}
+}
+
+// stringParams: decodes the first n params as strings, missing ones are empty
+func stringParams(raw json.RawMessage, n int) ([]string, int, error) {
+	params, err := decodeParams(raw)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	values := make([]string, n)
+	for i := 0; i < n && i < len(params); i++ {
+		if values[i], err = stringParam(params[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return values, len(params), nil
+}
+
+// isRequestingExistingSubscription: both the subscriber and the extraNonce1 are needed to resume a subscription
+func (p *subscribeParams) isRequestingExistingSubscription() bool {
+	return p.subscriber != "" && p.extraNonce1 != ""
+}
+
+func parseAuthorizeParams(raw json.RawMessage) (*authorizeParams, error) {
+	values, _, err := stringParams(raw, 2)
+	if err != nil {
+		return nil, err
+	}
+	if values[0] == "" {
+		return nil, errParamsMalformed
+	}
+
+	return &authorizeParams{
+		username: values[0],
+		password: values[1],
+	}, nil
+}
+
+// parseSubscribeParams: extra params, like the host and port sent by some proxies, are ignored
+func parseSubscribeParams(raw json.RawMessage) (*subscribeParams, error) {
+	values, _, err := stringParams(raw, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &subscribeParams{
+		subscriber:  values[0],
+		extraNonce1: values[1],
+	}, nil
+}
+
+func parseConfigureParams(raw json.RawMessage) (*configureParams, error) {
+	params, err := decodeParams(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) < 1 {
+		return nil, errParamsMalformed
+	}
+
+	p := &configureParams{}
+	if err := json.Unmarshal(params[0], &p.extensions); err != nil {
+		return nil, errParamsMalformed
+	}
+	if len(params) > 1 {
+		if err := json.Unmarshal(params[1], &p.options); err != nil {
+			return nil, errParamsMalformed
+		}
+	}
+	return p, nil
+}
+
+// option: decodes the option of an extension into v, returning false when the miner didn't send it
+func (p *configureParams) option(name string, v interface{}) (bool, error) {
+	raw, ok := p.options[name]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, errParamsMalformed
+	}
+	return true, nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"encoding/hex"
+	"fmt"
+	"stratum-server/bitcoin"
+	"stratum-server/config"
+)
+
+var (
+	errMissingPayoutAddress  = fmt.Errorf("missing payout address")
+	errPayoutAddressMismatch = fmt.Errorf("connection already mining for another payout address")
+)
+
+func (s *service) isSoloMode() bool {
+	return s.poolConfig.Mode == config.MiningModeSolo
+}
+
+// coinbase: returns the coinbase parts for the connection. In solo mode the coinbase pays the whole
+// block reward to the connection's payout address, so it's built for every connection
+func (ws *webSocket) coinbase(job *Job, extraNonce2 int64) (string, string, error) {
+	if !ws.svc.isSoloMode() || job.blockTemplate == nil {
+		return job.Coinb1, job.Coinb2, nil
+	}
+
+	address := ws.getPayoutAddress()
+	if address == nil {
+		return "", "", errMissingPayoutAddress
+	}
+	coinb1, coinb2, err := job.blockTemplate.Coinbase(address.Script, []byte(ws.svc.poolConfig.CoinbaseTag), extraNonce1Size+int(extraNonce2))
+	if err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(coinb1), hex.EncodeToString(coinb2), nil
+}
+
+func (ws *webSocket) getPayoutAddress() *bitcoin.Address {
+	ws.sessionMu.RLock()
+	defer ws.sessionMu.RUnlock()
+	return ws.payoutAddress
+}
+
+// setPayoutAddress: keeps the first authorized address, returning true if it was set. In solo mode the coinbase pays
+// every block found by the connection to that address, so other addresses are refused.
+func (ws *webSocket) setPayoutAddress(address *bitcoin.Address) (bool, error) {
+	ws.sessionMu.Lock()
+	defer ws.sessionMu.Unlock()
+
+	if address == nil {
+		return false, nil
+	}
+	if ws.payoutAddress != nil {
+		if ws.svc.isSoloMode() && ws.payoutAddress.String != address.String {
+			return false, errPayoutAddressMismatch
+		}
+		return false, nil
+	}
+	ws.payoutAddress = address
+	return true, nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"stratum-server/bitcoin"
+	"stratum-server/config"
+	"stratum-server/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	soloAliceAddress = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+	soloBobAddress   = "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy"
+	soloCoinbaseTag  = "/solo/"
+	soloReward       = int64(625000000)
+)
+
+func newSoloSession(t *testing.T, mode string) *webSocket {
+	svc := NewService(newSubscriptionsRepository(1), &config.Config{
+		AuthMode: config.AuthModeAddress,
+		Network:  "mainnet",
+		PoolConfig: config.PoolConfig{
+			Mode:         mode,
+			PayoutScript: "76a914000000000000000000000000000000000000000088ac",
+			CoinbaseTag:  soloCoinbaseTag,
+		},
+		VardiffConfig: config.VardiffConfig{InitialDifficulty: 1},
+	}, nil, nil)
+
+	job, err := svc.newJob(&template.Template{
+		Version:       0x20000000,
+		Height:        700000,
+		PrevHash:      make([]byte, 32),
+		Bits:          0x1d00ffff,
+		CurTime:       1623000000,
+		CoinbaseValue: soloReward,
+	})
+	assert.NoError(t, err)
+	job.CleanJobs = true
+	svc.jobs.add(job)
+
+	return &webSocket{
+		svc:          svc,
+		inboundMsg:   make(chan []byte, 16),
+		miningConfig: miningConfig{extraNonce2: svc.GetExtraNonce2()},
+		vardiff:      newVardiff(svc.vardiffConfig),
+		workers:      make(map[string]*worker),
+	}
+}
+
+// soloOutput: the hex encoded output paying the whole reward to the address, as found in coinb2
+func soloOutput(t *testing.T, address string) string {
+	a, err := bitcoin.DecodeAddress(address, "mainnet")
+	assert.NoError(t, err)
+
+	value := make([]byte, 8)
+	binary.LittleEndian.PutUint64(value, uint64(soloReward))
+	return hex.EncodeToString([]byte(soloCoinbaseTag)) + "ffffffff" + "01" + hex.EncodeToString(value) +
+		hex.EncodeToString(bitcoin.VarInt(uint64(len(a.Script)))) + hex.EncodeToString(a.Script)
+}
+
+func TestWebSocket_coinbase(t *testing.T) {
+	tests := []struct {
+		name           string
+		mode           string
+		username       string
+		expectedOutput string
+		expectedError  error
+	}{
+		{
+			name:           "solo coinbase pays a segwit address",
+			mode:           config.MiningModeSolo,
+			username:       soloAliceAddress + ".rig1",
+			expectedOutput: soloOutput(t, soloAliceAddress),
+		},
+		{
+			name:           "solo coinbase pays a P2SH address",
+			mode:           config.MiningModeSolo,
+			username:       soloBobAddress,
+			expectedOutput: soloOutput(t, soloBobAddress),
+		},
+		{
+			name:          "error without payout address in solo mode",
+			mode:          config.MiningModeSolo,
+			expectedError: errMissingPayoutAddress,
+		},
+		{
+			name:     "pool coinbase is shared by every connection",
+			mode:     config.MiningModePool,
+			username: soloAliceAddress,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := newSoloSession(t, tt.mode)
+			if tt.username != "" {
+				w, err := ws.svc.authorize(tt.username, "")
+				assert.NoError(t, err)
+				_, err = ws.setPayoutAddress(w.address)
+				assert.NoError(t, err)
+			}
+			job := ws.svc.jobs.currentJob()
+
+			coinb1, coinb2, err := ws.coinbase(job, ws.getExtraNonce2())
+			assert.Equal(t, tt.expectedError, err)
+			switch {
+			case tt.expectedError != nil:
+			case tt.expectedOutput == "":
+				assert.Equal(t, job.Coinb1, coinb1)
+				assert.Equal(t, job.Coinb2, coinb2)
+			default:
+				assert.NotEmpty(t, coinb1)
+				assert.True(t, strings.HasPrefix(coinb2, tt.expectedOutput), "coinb2 %s doesn't pay %s", coinb2, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestWebSocket_handleMiningAuthorize_solo(t *testing.T) {
+	const (
+		subscribe     = `{"id":1,"method":"mining.subscribe","params":[]}`
+		authorize     = `{"id":2,"method":"mining.authorize","params":["` + soloAliceAddress + `.rig1",""]}`
+		authorizeRig2 = `{"id":3,"method":"mining.authorize","params":["` + soloAliceAddress + `.rig2",""]}`
+		authorizeBob  = `{"id":4,"method":"mining.authorize","params":["` + soloBobAddress + `.rig1",""]}`
+	)
+
+	tests := []struct {
+		name     string
+		messages []string
+		// methods of the notifications, or ids of the responses, in the order they're written
+		expected []string
+	}{
+		{
+			name:     "authorize then subscribe",
+			messages: []string{authorize, subscribe},
+			expected: []string{"2", "1", miningSetDifficultyKey, miningNotifyKey},
+		},
+		{
+			name:     "subscribe then authorize",
+			messages: []string{subscribe, authorize},
+			// the job can't be built until the connection has a payout address
+			expected: []string{"1", miningSetDifficultyKey, "2", miningNotifyKey},
+		},
+		{
+			name:     "workers of the same address",
+			messages: []string{subscribe, authorize, authorizeRig2},
+			expected: []string{"1", miningSetDifficultyKey, "2", miningNotifyKey, "3"},
+		},
+		{
+			name:     "error with workers of another address",
+			messages: []string{subscribe, authorize, authorizeBob},
+			expected: []string{"1", miningSetDifficultyKey, "2", miningNotifyKey, "4 error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := newSoloSession(t, config.MiningModeSolo)
+			for _, msg := range tt.messages {
+				ws.handleMessage([]byte(msg))
+			}
+
+			var written []string
+			for len(ws.inboundMsg) > 0 {
+				var msg struct {
+					ID     json.RawMessage `json:"id"`
+					Method string          `json:"method"`
+					Params []interface{}   `json:"params"`
+					Error  []interface{}   `json:"error"`
+				}
+				assert.NoError(t, json.Unmarshal(<-ws.inboundMsg, &msg))
+				switch {
+				case msg.Method == miningNotifyKey:
+					// every job pays the first authorized address
+					assert.True(t, strings.HasPrefix(msg.Params[3].(string), soloOutput(t, soloAliceAddress)))
+					written = append(written, msg.Method)
+				case msg.Method != "":
+					written = append(written, msg.Method)
+				case msg.Error != nil:
+					assert.Equal(t, errPayoutAddressMismatch.Error(), msg.Error[1])
+					written = append(written, string(msg.ID)+" error")
+				default:
+					written = append(written, string(msg.ID))
+				}
+			}
+			assert.Equal(t, tt.expected, written)
+			assert.Equal(t, soloAliceAddress, ws.getPayoutAddress().String)
+		})
+	}
+}
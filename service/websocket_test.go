@@ -0,0 +1,197 @@
+package service
+
+import (
+	"stratum-server/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocket_handleMessage(t *testing.T) {
+	tests := []struct {
+		name             string
+		msg              string
+		expectedResponse string
+	}{
+		{
+			name:             "string id and numeric password",
+			msg:              `{"id":"abc","method":"mining.authorize","params":["alice.rig1",123]}`,
+			expectedResponse: `{"id":"abc","result":true,"error":null}`,
+		},
+		{
+			name:             "null id",
+			msg:              `{"id":null,"method":"mining.authorize","params":["alice","x"]}`,
+			expectedResponse: `{"id":null,"result":true,"error":null}`,
+		},
+		{
+			name:             "id echoed verbatim",
+			msg:              `{"id":1.5e3,"method":"mining.authorize","params":["alice"]}`,
+			expectedResponse: `{"id":1.5e3,"result":true,"error":null}`,
+		},
+		{
+			name:             "error with missing params keeps the id",
+			msg:              `{"id":7,"method":"mining.authorize","params":[]}`,
+			expectedResponse: `{"id":7,"result":null,"error":[-32602,"Invalid params",null]}`,
+		},
+		{
+			name:             "error with object param keeps the id",
+			msg:              `{"id":8,"method":"mining.authorize","params":[{"user":"alice"}]}`,
+			expectedResponse: `{"id":8,"result":null,"error":[-32602,"Invalid params",null]}`,
+		},
+		{
+			name:             "error with submit before subscribing",
+			msg:              `{"id":9,"method":"mining.submit","params":["alice","1","00000000","495fab29","7c2bac1d"]}`,
+			expectedResponse: `{"id":9,"result":null,"error":[25,"Not subscribed",null]}`,
+		},
+		{
+			name:             "extranonce subscribe",
+			msg:              `{"id":12,"method":"mining.extranonce.subscribe","params":[]}`,
+			expectedResponse: `{"id":12,"result":true,"error":null}`,
+		},
+		{
+			name:             "error with extranonce subscribe object params",
+			msg:              `{"id":13,"method":"mining.extranonce.subscribe","params":{}}`,
+			expectedResponse: `{"id":13,"result":null,"error":[-32602,"Invalid params",null]}`,
+		},
+		{
+			name:             "error with unknown method",
+			msg:              `{"id":"x-10","method":"mining.unknown","params":[1,{"a":true}]}`,
+			expectedResponse: `{"id":"x-10","result":null,"error":[-32601,"Method not found",null]}`,
+		},
+		{
+			name:             "error without method keeps the id",
+			msg:              `{"id":11}`,
+			expectedResponse: `{"id":11,"result":null,"error":[-32600,"Invalid Request",null]}`,
+		},
+		{
+			name:             "error with non string method keeps the id",
+			msg:              `{"id":1,"method":5}`,
+			expectedResponse: `{"id":1,"result":null,"error":[-32600,"Invalid Request",null]}`,
+		},
+		{
+			name:             "error with non string method before the id keeps the id",
+			msg:              `{"method":5,"id":"abc"}`,
+			expectedResponse: `{"id":"abc","result":null,"error":[-32600,"Invalid Request",null]}`,
+		},
+		{
+			name:             "error with non object request",
+			msg:              `5`,
+			expectedResponse: `{"id":null,"result":null,"error":[-32600,"Invalid Request",null]}`,
+		},
+		{
+			name:             "error with object id",
+			msg:              `{"id":{"a":1},"method":"mining.authorize"}`,
+			expectedResponse: `{"id":null,"result":null,"error":[-32600,"Invalid Request",null]}`,
+		},
+		{
+			name:             "error with invalid JSON",
+			msg:              `{"id":1,`,
+			expectedResponse: `{"id":null,"result":null,"error":[-32700,"Parse error",null]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(nil, &config.Config{AuthMode: config.AuthModeNone}, nil, nil)
+			ws := &webSocket{
+				svc:        svc,
+				inboundMsg: make(chan []byte, 1),
+				vardiff:    newVardiff(svc.vardiffConfig),
+				workers:    make(map[string]*worker),
+			}
+
+			ws.handleMessage([]byte(tt.msg))
+			assert.Equal(t, tt.expectedResponse, string(<-ws.inboundMsg))
+		})
+	}
+}
+
+func TestWebSocket_handleMessage_batch(t *testing.T) {
+	tests := []struct {
+		name                  string
+		dialect               string
+		msg                   string
+		expectedResponse      string
+		expectedNotifications []string
+	}{
+		{
+			name:    "responses in order followed by notifications",
+			dialect: config.ProtocolDialectStratum,
+			msg:     `[{"id":1,"method":"mining.subscribe","params":["m"]},{"id":2,"method":"mining.authorize","params":["worker","x"]},{"id":"3","method":"mining.unknown"},5]`,
+			expectedResponse: `[` +
+				`{"id":1,"result":[[["mining.set_difficulty","b4b6693b-72ad-4d8a-8a0f-1bf6d0c8f4a1"],["mining.notify","3c0b9f3e-5a53-4d7e-9f4c-2a1d8f1e6b7c"]],"ffff001d",4],"error":null},` +
+				`{"id":2,"result":true,"error":null},` +
+				`{"id":"3","result":null,"error":[-32601,"Method not found",null]},` +
+				`{"id":null,"result":null,"error":[-32600,"Invalid Request",null]}]`,
+			expectedNotifications: []string{
+				`{"id":null,"method":"mining.set_difficulty","params":[1]}`,
+				miningNotifyKey,
+			},
+		},
+		{
+			name:    "jsonrpc2 notifications without id",
+			dialect: config.ProtocolDialectJSONRPC2,
+			msg:     `[{"id":1,"method":"mining.subscribe"}]`,
+			expectedResponse: `[` +
+				`{"jsonrpc":"2.0","id":1,"result":[[["mining.set_difficulty","b4b6693b-72ad-4d8a-8a0f-1bf6d0c8f4a1"],["mining.notify","3c0b9f3e-5a53-4d7e-9f4c-2a1d8f1e6b7c"]],"ffff001d",4]}]`,
+			expectedNotifications: []string{
+				`{"jsonrpc":"2.0","method":"mining.set_difficulty","params":[1]}`,
+				miningNotifyKey,
+			},
+		},
+		{
+			name:             "error with empty batch",
+			dialect:          config.ProtocolDialectStratum,
+			msg:              ` []`,
+			expectedResponse: `{"id":null,"result":null,"error":[-32600,"Invalid Request",null]}`,
+		},
+		{
+			name:             "error with invalid JSON",
+			dialect:          config.ProtocolDialectStratum,
+			msg:              `[{"id":1,"method":"mining.subscribe"}`,
+			expectedResponse: `{"id":null,"result":null,"error":[-32700,"Parse error",null]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := newConformanceSession()
+			ws.svc.protocolDialect = tt.dialect
+
+			ws.handleMessage([]byte(tt.msg))
+			assert.Equal(t, tt.expectedResponse, string(<-ws.inboundMsg))
+			for _, expected := range tt.expectedNotifications {
+				msg := <-ws.inboundMsg
+				if expected == miningNotifyKey {
+					// jobs are checked in the conformance transcripts
+					assert.Contains(t, string(msg), `"method":"mining.notify"`)
+					continue
+				}
+				assert.Equal(t, expected, string(msg))
+			}
+			assertNoPendingMessages(t, ws)
+		})
+	}
+}
+
+func TestWebSocket_WriteMsg(t *testing.T) {
+	ws := &webSocket{
+		inboundMsg: make(chan []byte, 2),
+		close:      make(chan struct{}),
+	}
+
+	ws.WriteMsg(newSetDifficultyNotification(1))
+	ws.WriteMsg(newSetDifficultyNotification(2))
+	select {
+	case <-ws.close:
+		t.Fatal("connection closed before its queue was full")
+	default:
+	}
+
+	// the slow connection is closed instead of dropping the message
+	ws.WriteMsg(newSetDifficultyNotification(4))
+	select {
+	case <-ws.close:
+	default:
+		t.Fatal("slow connection not closed")
+	}
+	assert.Len(t, ws.inboundMsg, 2)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"stratum-server/config"
+	"sync"
+	"time"
+)
+
+const (
+	// relative changes below this threshold don't trigger a new mining.set_difficulty
+	vardiffTolerance = 0.1
+	// every connection is retargeted on its own schedule, checked a few times per interval so that none of them waits
+	// for almost two intervals
+	vardiffChecksPerInterval = 4
+)
+
+type vardiff struct {
+	mu         sync.Mutex
+	cfg        config.VardiffConfig
+	difficulty float64
+	// difficulty in use before the last retarget, still valid for jobs sent before it
+	previousDifficulty float64
+	changedAt          time.Time
+	shares             int64
+	lastRetarget       time.Time
+}
+
+func newVardiff(cfg config.VardiffConfig) *vardiff {
+	now := time.Now()
+	return &vardiff{
+		cfg:                cfg,
+		difficulty:         cfg.InitialDifficulty,
+		previousDifficulty: cfg.InitialDifficulty,
+		changedAt:          now,
+		lastRetarget:       now,
+	}
+}
+
+// RunVardiff: periodically retargets the difficulty of every subscribed connection. The difficulty belongs to the
+// connection, so the workers sharing it are retargeted together
+func (s *service) RunVardiff(ctx context.Context) {
+	ticker := time.NewTicker(s.vardiffConfig.RetargetInterval / vardiffChecksPerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.hub.forEach(func(ws *webSocket) {
+				if difficulty, changed := ws.vardiff.retarget(now); changed {
+					log.Printf("[vardiff] retargeting extraNonce1 %08x to difficulty %f", ws.getSubscription().extraNonce1, difficulty)
+					ws.sendDifficulty(difficulty)
+				}
+			})
+		}
+	}
+}
+
+func (v *vardiff) currentDifficulty() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.difficulty
+}
+
+// shareDifficulty: returns the difficulty a share must meet for a job created at the given time
+func (v *vardiff) shareDifficulty(jobCreatedAt time.Time) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if jobCreatedAt.Before(v.changedAt) {
+		return math.Min(v.difficulty, v.previousDifficulty)
+	}
+	return v.difficulty
+}
+
+func (v *vardiff) addShare() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.shares++
+}
+
+// retarget: estimates the difficulty needed to reach the target share time, smoothing it with the current one
+func (v *vardiff) retarget(now time.Time) (float64, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	elapsed := now.Sub(v.lastRetarget)
+	if elapsed < v.cfg.RetargetInterval {
+		return v.difficulty, false
+	}
+
+	// without shares, assume that one was about to be found
+	shares := math.Max(float64(v.shares), 1)
+	shareTime := elapsed.Seconds() / shares
+	estimation := v.difficulty * v.cfg.TargetShareTime.Seconds() / shareTime
+
+	difficulty := v.difficulty + v.cfg.Smoothing*(estimation-v.difficulty)
+	difficulty = math.Max(v.cfg.MinDifficulty, math.Min(v.cfg.MaxDifficulty, difficulty))
+
+	v.shares = 0
+	v.lastRetarget = now
+
+	if math.Abs(difficulty-v.difficulty)/v.difficulty < vardiffTolerance {
+		return v.difficulty, false
+	}
+
+	v.previousDifficulty = v.difficulty
+	v.difficulty = difficulty
+	v.changedAt = now
+	return difficulty, true
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"stratum-server/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVardiff_retarget(t *testing.T) {
+	cfg := config.VardiffConfig{
+		InitialDifficulty: 64,
+		MinDifficulty:     16,
+		MaxDifficulty:     256,
+		TargetShareTime:   10 * time.Second,
+		RetargetInterval:  60 * time.Second,
+		Smoothing:         0.5,
+	}
+
+	tests := []struct {
+		name               string
+		shares             int64
+		elapsed            time.Duration
+		expectedDifficulty float64
+		expectedChanged    bool
+	}{
+		{
+			name:               "no retarget before interval",
+			shares:             60,
+			elapsed:            30 * time.Second,
+			expectedDifficulty: 64,
+		},
+		{
+			name:               "no retarget within tolerance",
+			shares:             6,
+			elapsed:            60 * time.Second,
+			expectedDifficulty: 64,
+		},
+		{
+			name:               "increase difficulty with fast shares",
+			shares:             12,
+			elapsed:            60 * time.Second,
+			expectedDifficulty: 96,
+			expectedChanged:    true,
+		},
+		{
+			name:               "decrease difficulty with slow shares",
+			shares:             3,
+			elapsed:            60 * time.Second,
+			expectedDifficulty: 48,
+			expectedChanged:    true,
+		},
+		{
+			name:               "max difficulty bound",
+			shares:             600,
+			elapsed:            60 * time.Second,
+			expectedDifficulty: 256,
+			expectedChanged:    true,
+		},
+		{
+			name:               "decrease difficulty without shares",
+			elapsed:            10 * time.Minute,
+			expectedDifficulty: 32 + 32.0/60,
+			expectedChanged:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVardiff(cfg)
+			v.shares = tt.shares
+
+			difficulty, changed := v.retarget(v.lastRetarget.Add(tt.elapsed))
+			assert.InDelta(t, tt.expectedDifficulty, difficulty, 1e-9)
+			assert.Equal(t, tt.expectedChanged, changed)
+		})
+	}
+}
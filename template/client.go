@@ -0,0 +1,97 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"stratum-server/config"
+	"sync/atomic"
+)
+
+// RPCError represents an error returned by the node.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// Client performs JSON-RPC calls against a bitcoind-compatible node.
+type Client struct {
+	url      string
+	user     string
+	password string
+	http     *http.Client
+	counter  uint64
+}
+
+// NewClient: creates a client for the configured node. Requests don't time out, since longpoll calls
+// are expected to block until a new template is available; use the context instead.
+func NewClient(cfg config.NodeConfig) *Client {
+	return &Client{
+		url:      cfg.RPCURL,
+		user:     cfg.RPCUser,
+		password: cfg.RPCPassword,
+		http:     &http.Client{},
+	}
+}
+
+// Call: performs the given RPC method, decoding its result into the result param when it's not nil
+func (c *Client) Call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(&rpcRequest{
+		JSONRPC: "1.0",
+		ID:      atomic.AddUint64(&c.counter, 1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	rpcRes := &rpcResponse{}
+	if err := json.NewDecoder(res.Body).Decode(rpcRes); err != nil {
+		return fmt.Errorf("error decoding %s response with status %d: %v", method, res.StatusCode, err)
+	}
+	if rpcRes.Error != nil {
+		return rpcRes.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(rpcRes.Result, result)
+}
@@ -0,0 +1,128 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	getBlockTemplateMethod = "getblocktemplate"
+	submitBlockMethod      = "submitblock"
+	// result stored for blocks accepted by the node
+	SubmitBlockAccepted = "accepted"
+	// delay before retrying after a failed getblocktemplate
+	retryDelay = 5 * time.Second
+)
+
+// Source describes a provider of block templates.
+type Source interface {
+	// Run: fetches templates until the context is done, sending every new one through the channel
+	Run(ctx context.Context, templates chan<- *Template)
+	// SubmitBlock: sends a solved block to the node, returning the submission result
+	SubmitBlock(ctx context.Context, block []byte) (string, error)
+	// LastFetchAt: time of the last template successfully fetched, even if it didn't change, zero if none was
+	LastFetchAt() time.Time
+}
+
+type rpcSource struct {
+	client       *Client
+	pollInterval time.Duration
+	// unix nanoseconds, read from other routines
+	lastFetchAt int64
+}
+
+// NewRPCSource: creates a source that polls getblocktemplate, using longpoll when the node supports it
+func NewRPCSource(client *Client, pollInterval time.Duration) Source {
+	return &rpcSource{
+		client:       client,
+		pollInterval: pollInterval,
+	}
+}
+
+func (s *rpcSource) Run(ctx context.Context, templates chan<- *Template) {
+	var current *Template
+	for {
+		longPollID := ""
+		if current != nil {
+			longPollID = current.LongPollID
+		}
+
+		t, err := s.getBlockTemplate(ctx, longPollID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("error getting block template: %v", err)
+			if !sleep(ctx, retryDelay) {
+				return
+			}
+			continue
+		}
+		atomic.StoreInt64(&s.lastFetchAt, time.Now().UnixNano())
+
+		if current == nil || longPollID != "" || !bytes.Equal(current.PrevHash, t.PrevHash) || current.Fees != t.Fees {
+			current = t
+			select {
+			case templates <- t:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// longpoll calls already block until there's a new template
+		if current.LongPollID == "" && !sleep(ctx, s.pollInterval) {
+			return
+		}
+	}
+}
+
+func (s *rpcSource) SubmitBlock(ctx context.Context, block []byte) (string, error) {
+	// null result means the block was accepted, otherwise the rejection reason is returned
+	var result *string
+	if err := s.client.Call(ctx, submitBlockMethod, []interface{}{hex.EncodeToString(block)}, &result); err != nil {
+		return "", err
+	}
+	if result == nil {
+		return SubmitBlockAccepted, nil
+	}
+	return *result, nil
+}
+
+func (s *rpcSource) LastFetchAt() time.Time {
+	fetchedAt := atomic.LoadInt64(&s.lastFetchAt)
+	if fetchedAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, fetchedAt)
+}
+
+func (s *rpcSource) getBlockTemplate(ctx context.Context, longPollID string) (*Template, error) {
+	request := map[string]interface{}{
+		"rules": []string{"segwit"},
+	}
+	if longPollID != "" {
+		request["longpollid"] = longPollID
+	}
+
+	res := &GetBlockTemplateResult{}
+	if err := s.client.Call(ctx, getBlockTemplateMethod, []interface{}{request}, res); err != nil {
+		return nil, err
+	}
+	return NewTemplate(res)
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
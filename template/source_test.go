@@ -0,0 +1,85 @@
+package template_test
+
+import (
+	"context"
+	"stratum-server/config"
+	"stratum-server/template"
+	"stratum-server/template/templatetest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRPCSource_Run(t *testing.T) {
+	node := templatetest.NewFakeNode(templatetest.NewGetBlockTemplateResult(100))
+	defer node.Close()
+
+	client := template.NewClient(config.NodeConfig{RPCURL: node.URL()})
+	source := template.NewRPCSource(client, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	templates := make(chan *template.Template)
+	go source.Run(ctx, templates)
+
+	assert.Equal(t, int64(100), receive(t, templates).Height)
+
+	// pending longpoll must be answered with the new template
+	node.SetTemplate(templatetest.NewGetBlockTemplateResult(101))
+	assert.Equal(t, int64(101), receive(t, templates).Height)
+}
+
+func TestRPCSource_LastFetchAt(t *testing.T) {
+	node := templatetest.NewFakeNode(templatetest.NewGetBlockTemplateResult(100))
+	defer node.Close()
+	node.DisableLongPoll()
+
+	source := template.NewRPCSource(template.NewClient(config.NodeConfig{RPCURL: node.URL()}), 10*time.Millisecond)
+	assert.True(t, source.LastFetchAt().IsZero())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	templates := make(chan *template.Template)
+	go source.Run(ctx, templates)
+
+	assert.Equal(t, int64(100), receive(t, templates).Height)
+	fetchedAt := source.LastFetchAt()
+	assert.False(t, fetchedAt.IsZero())
+
+	// the template doesn't change, but every poll is still recorded
+	assert.Eventually(t, func() bool {
+		return source.LastFetchAt().After(fetchedAt)
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case tmpl := <-templates:
+		t.Fatalf("unchanged template at height %d sent again", tmpl.Height)
+	default:
+	}
+}
+
+func receive(t *testing.T, templates chan *template.Template) *template.Template {
+	select {
+	case tmpl := <-templates:
+		return tmpl
+	case <-time.After(5 * time.Second):
+		t.Fatal("template not received")
+		return nil
+	}
+}
+
+func TestRPCSource_SubmitBlock(t *testing.T) {
+	node := templatetest.NewFakeNode(templatetest.NewGetBlockTemplateResult(100))
+	defer node.Close()
+
+	source := template.NewRPCSource(template.NewClient(config.NodeConfig{RPCURL: node.URL()}), time.Hour)
+
+	result, err := source.SubmitBlock(context.Background(), []byte{0x01, 0x02})
+	assert.NoError(t, err)
+	assert.Equal(t, template.SubmitBlockAccepted, result)
+	assert.Equal(t, []string{"0102"}, node.SubmittedBlocks())
+
+	result, err = source.SubmitBlock(context.Background(), []byte{0x01, 0x02})
+	assert.NoError(t, err)
+	assert.Equal(t, "duplicate", result)
+}
@@ -0,0 +1,214 @@
+package template
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"stratum-server/bitcoin"
+	"time"
+)
+
+const (
+	coinbaseVersion uint32 = 2
+)
+
+// GetBlockTemplateResult represents the getblocktemplate response, as defined in BIP22 and BIP23.
+type GetBlockTemplateResult struct {
+	Version                  uint32              `json:"version"`
+	PreviousBlockHash        string              `json:"previousblockhash"`
+	Transactions             []TransactionResult `json:"transactions"`
+	CoinbaseValue            int64               `json:"coinbasevalue"`
+	LongPollID               string              `json:"longpollid,omitempty"`
+	Target                   string              `json:"target"`
+	MinTime                  int64               `json:"mintime"`
+	CurTime                  int64               `json:"curtime"`
+	Bits                     string              `json:"bits"`
+	Height                   int64               `json:"height"`
+	DefaultWitnessCommitment string              `json:"default_witness_commitment,omitempty"`
+	Rules                    []string            `json:"rules,omitempty"`
+	VBAvailable              map[string]int      `json:"vbavailable,omitempty"`
+	Mutable                  []string            `json:"mutable,omitempty"`
+}
+
+// TransactionResult represents every transaction within the getblocktemplate response.
+type TransactionResult struct {
+	Data string `json:"data"`
+	TxID string `json:"txid"`
+	Hash string `json:"hash"`
+	Fee  int64  `json:"fee"`
+}
+
+// Template represents a decoded block template.
+type Template struct {
+	Version       uint32
+	Height        int64
+	PrevHash      []byte
+	Bits          uint32
+	CurTime       uint32
+	CoinbaseValue int64
+	// raw transactions, excluding the coinbase
+	Transactions [][]byte
+	// transaction ids in internal byte order, excluding the coinbase
+	TxHashes          [][]byte
+	Fees              int64
+	WitnessCommitment []byte
+	LongPollID        string
+	ReceivedAt        time.Time
+
+	merkleBranch [][]byte
+}
+
+// JobParts represents the fields needed to build a mining.notify message, hex encoded as
+// expected by Stratum V1.
+type JobParts struct {
+	PrevHash     string
+	Coinb1       string
+	Coinb2       string
+	MerkleBranch []string
+	Version      string
+	NBits        string
+	NTime        string
+}
+
+// NewTemplate: decodes the getblocktemplate response
+func NewTemplate(res *GetBlockTemplateResult) (*Template, error) {
+	prevHash, err := hex.DecodeString(res.PreviousBlockHash)
+	if err != nil || len(prevHash) != 32 {
+		return nil, fmt.Errorf("invalid previousblockhash: %s", res.PreviousBlockHash)
+	}
+	bits, err := hex.DecodeString(res.Bits)
+	if err != nil || len(bits) != 4 {
+		return nil, fmt.Errorf("invalid bits: %s", res.Bits)
+	}
+
+	t := &Template{
+		Version:       res.Version,
+		Height:        res.Height,
+		PrevHash:      bitcoin.ReverseBytes(prevHash),
+		Bits:          binary.BigEndian.Uint32(bits),
+		CurTime:       uint32(res.CurTime),
+		CoinbaseValue: res.CoinbaseValue,
+		LongPollID:    res.LongPollID,
+		ReceivedAt:    time.Now(),
+	}
+
+	for _, tx := range res.Transactions {
+		data, err := hex.DecodeString(tx.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transaction data for %s: %v", tx.TxID, err)
+		}
+		txID, err := hex.DecodeString(tx.TxID)
+		if err != nil || len(txID) != 32 {
+			return nil, fmt.Errorf("invalid txid: %s", tx.TxID)
+		}
+		t.Transactions = append(t.Transactions, data)
+		t.TxHashes = append(t.TxHashes, bitcoin.ReverseBytes(txID))
+		t.Fees += tx.Fee
+	}
+
+	if res.DefaultWitnessCommitment != "" {
+		if t.WitnessCommitment, err = hex.DecodeString(res.DefaultWitnessCommitment); err != nil {
+			return nil, fmt.Errorf("invalid default_witness_commitment: %v", err)
+		}
+	}
+
+	t.merkleBranch = bitcoin.MerkleBranch(t.TxHashes)
+	return t, nil
+}
+
+// JobParts: builds the coinbase paying to the given script and splits it around the extranonce
+// placeholder, together with the header fields
+func (t *Template) JobParts(payoutScript []byte, coinbaseTag []byte, extraNonceSize int) (*JobParts, error) {
+	coinb1, coinb2, err := t.Coinbase(payoutScript, coinbaseTag, extraNonceSize)
+	if err != nil {
+		return nil, err
+	}
+
+	merkleBranch := make([]string, 0, len(t.merkleBranch))
+	for _, h := range t.merkleBranch {
+		merkleBranch = append(merkleBranch, hex.EncodeToString(h))
+	}
+
+	return &JobParts{
+		PrevHash:     hex.EncodeToString(bitcoin.SwapWords(t.PrevHash)),
+		Coinb1:       hex.EncodeToString(coinb1),
+		Coinb2:       hex.EncodeToString(coinb2),
+		MerkleBranch: merkleBranch,
+		Version:      fmt.Sprintf("%08x", t.Version),
+		NBits:        fmt.Sprintf("%08x", t.Bits),
+		NTime:        fmt.Sprintf("%08x", t.CurTime),
+	}, nil
+}
+
+// Coinbase: returns the coinbase transaction, without witness, split around the extranonce
+func (t *Template) Coinbase(payoutScript []byte, coinbaseTag []byte, extraNonceSize int) ([]byte, []byte, error) {
+	if len(payoutScript) == 0 {
+		return nil, nil, fmt.Errorf("missing payout script")
+	}
+
+	height := bitcoin.ScriptNumPush(t.Height)
+	scriptSigLen := len(height) + extraNonceSize + len(coinbaseTag)
+	if scriptSigLen > 100 {
+		return nil, nil, fmt.Errorf("coinbase script too long: %d bytes", scriptSigLen)
+	}
+
+	coinb1 := &bytes.Buffer{}
+	_ = binary.Write(coinb1, binary.LittleEndian, coinbaseVersion)
+	coinb1.WriteByte(1)
+	coinb1.Write(make([]byte, 32))
+	coinb1.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	coinb1.Write(bitcoin.VarInt(uint64(scriptSigLen)))
+	coinb1.Write(height)
+
+	coinb2 := &bytes.Buffer{}
+	coinb2.Write(coinbaseTag)
+	coinb2.Write([]byte{0xff, 0xff, 0xff, 0xff})
+
+	outputs := 1
+	if t.WitnessCommitment != nil {
+		outputs++
+	}
+	coinb2.Write(bitcoin.VarInt(uint64(outputs)))
+	_ = binary.Write(coinb2, binary.LittleEndian, t.CoinbaseValue)
+	coinb2.Write(bitcoin.VarInt(uint64(len(payoutScript))))
+	coinb2.Write(payoutScript)
+	if t.WitnessCommitment != nil {
+		_ = binary.Write(coinb2, binary.LittleEndian, int64(0))
+		coinb2.Write(bitcoin.VarInt(uint64(len(t.WitnessCommitment))))
+		coinb2.Write(t.WitnessCommitment)
+	}
+	// lock time
+	coinb2.Write([]byte{0, 0, 0, 0})
+
+	return coinb1.Bytes(), coinb2.Bytes(), nil
+}
+
+// Block: serializes the full block for submitblock, adding the coinbase witness when the template requires it
+func (t *Template) Block(header []byte, coinbase []byte) []byte {
+	b := &bytes.Buffer{}
+	b.Write(header)
+	b.Write(bitcoin.VarInt(uint64(len(t.Transactions) + 1)))
+	if t.WitnessCommitment != nil {
+		b.Write(witnessCoinbase(coinbase))
+	} else {
+		b.Write(coinbase)
+	}
+	for _, tx := range t.Transactions {
+		b.Write(tx)
+	}
+	return b.Bytes()
+}
+
+// witnessCoinbase: adds the segwit marker and flag, and the witness reserved value, to the coinbase
+func witnessCoinbase(coinbase []byte) []byte {
+	b := &bytes.Buffer{}
+	b.Write(coinbase[:4])
+	b.Write([]byte{0x00, 0x01})
+	b.Write(coinbase[4 : len(coinbase)-4])
+	// one stack item of 32 bytes
+	b.Write([]byte{0x01, 0x20})
+	b.Write(make([]byte, 32))
+	b.Write(coinbase[len(coinbase)-4:])
+	return b.Bytes()
+}
@@ -0,0 +1,114 @@
+package template
+
+import (
+	"encoding/hex"
+	"fmt"
+	"stratum-server/bitcoin"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate_JobParts(t *testing.T) {
+	payoutScript, _ := hex.DecodeString("0014751e76e8199196d454941c45d1b3a323f1433bd6")
+
+	tests := []struct {
+		name         string
+		transactions int
+		branchLength int
+	}{
+		{name: "only coinbase", transactions: 0, branchLength: 0},
+		{name: "one transaction", transactions: 1, branchLength: 1},
+		{name: "odd transactions", transactions: 2, branchLength: 2},
+		{name: "many transactions", transactions: 9, branchLength: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &GetBlockTemplateResult{
+				Version:                  0x20000000,
+				PreviousBlockHash:        "000000000000000000037c5a07b1d3e4a5a1d6a3c6f2d3a84a0e6df1bd3bf9a8",
+				CoinbaseValue:            625000000,
+				CurTime:                  1620000000,
+				Bits:                     "170e92aa",
+				Height:                   684000,
+				DefaultWitnessCommitment: "6a24aa21a9ede2f61c3f71d1defd3fa999dfa36953755c690689799962b48bebd836974e8cf9",
+			}
+			for i := 0; i < tt.transactions; i++ {
+				res.Transactions = append(res.Transactions, TransactionResult{
+					Data: fmt.Sprintf("%02x", i),
+					TxID: fmt.Sprintf("%064x", i+1),
+					Fee:  1000,
+				})
+			}
+
+			tmpl, err := NewTemplate(res)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1000*tt.transactions), tmpl.Fees)
+
+			parts, err := tmpl.JobParts(payoutScript, []byte("/test/"), 8)
+			assert.NoError(t, err)
+			assert.Len(t, parts.MerkleBranch, tt.branchLength)
+			assert.Equal(t, "20000000", parts.Version)
+			assert.Equal(t, "170e92aa", parts.NBits)
+			assert.Equal(t, "608f3d00", parts.NTime)
+			// BIP34 height right after the scriptSig length
+			assert.Equal(t, "03e06f0a", parts.Coinb1[len(parts.Coinb1)-8:])
+
+			coinbase, _ := hex.DecodeString(parts.Coinb1 + "0000000100000002" + parts.Coinb2)
+			branch := make([][]byte, 0, len(parts.MerkleBranch))
+			for _, h := range parts.MerkleBranch {
+				b, _ := hex.DecodeString(h)
+				branch = append(branch, b)
+			}
+			hashes := append([][]byte{bitcoin.DoubleSHA256(coinbase)}, tmpl.TxHashes...)
+			assert.Equal(t, merkleRoot(hashes), bitcoin.MerkleRootFromBranch(bitcoin.DoubleSHA256(coinbase), branch))
+		})
+	}
+}
+
+func merkleRoot(hashes [][]byte) []byte {
+	for len(hashes) > 1 {
+		if len(hashes)%2 == 1 {
+			hashes = append(hashes, hashes[len(hashes)-1])
+		}
+		var next [][]byte
+		for i := 0; i < len(hashes); i += 2 {
+			next = append(next, bitcoin.DoubleSHA256(append(append([]byte{}, hashes[i]...), hashes[i+1]...)))
+		}
+		hashes = next
+	}
+	return hashes[0]
+}
+
+func TestTemplate_Block(t *testing.T) {
+	coinbase, _ := hex.DecodeString("02000000" + "aabb" + "00000000")
+	header := make([]byte, bitcoin.HeaderSize)
+
+	tests := []struct {
+		name              string
+		witnessCommitment []byte
+		expectedCoinbase  string
+	}{
+		{
+			name:             "without witness commitment",
+			expectedCoinbase: "02000000aabb00000000",
+		},
+		{
+			name:              "with witness commitment",
+			witnessCommitment: []byte{0x6a},
+			expectedCoinbase:  "020000000001aabb0120" + fmt.Sprintf("%064x", 0) + "00000000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := &Template{
+				Transactions:      [][]byte{{0xcc}, {0xdd}},
+				WitnessCommitment: tt.witnessCommitment,
+			}
+
+			b := tmpl.Block(header, coinbase)
+			assert.Equal(t, header, b[:bitcoin.HeaderSize])
+			assert.Equal(t, "03"+tt.expectedCoinbase+"ccdd", hex.EncodeToString(b[bitcoin.HeaderSize:]))
+		})
+	}
+}
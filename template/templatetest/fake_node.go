@@ -0,0 +1,170 @@
+package templatetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"stratum-server/template"
+	"sync"
+)
+
+// FakeNode is an in-process bitcoind-compatible node to be used in tests. It serves getblocktemplate,
+// including longpoll, and records every submitblock call.
+type FakeNode struct {
+	server *httptest.Server
+
+	mu         sync.Mutex
+	template   *template.GetBlockTemplateResult
+	longPollID int
+	// nodes without longpoll support are polled instead
+	noLongPoll bool
+	// closed when a new template is set, waking up longpoll requests
+	updated chan struct{}
+	blocks  []string
+}
+
+type rpcRequest struct {
+	ID     interface{}       `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     interface{}        `json:"id"`
+	Result interface{}        `json:"result"`
+	Error  *template.RPCError `json:"error"`
+}
+
+// NewFakeNode: starts a fake node serving the given template
+func NewFakeNode(t *template.GetBlockTemplateResult) *FakeNode {
+	n := &FakeNode{
+		updated: make(chan struct{}),
+	}
+	n.setTemplate(t)
+	n.server = httptest.NewServer(http.HandlerFunc(n.serveHTTP))
+	return n
+}
+
+// NewGetBlockTemplateResult: returns a regtest template, whose target is met by almost every hash
+func NewGetBlockTemplateResult(height int64) *template.GetBlockTemplateResult {
+	return &template.GetBlockTemplateResult{
+		Version:           0x20000000,
+		PreviousBlockHash: fmt.Sprintf("%064x", height-1),
+		Transactions:      []template.TransactionResult{},
+		CoinbaseValue:     5000000000,
+		Target:            "7fffff0000000000000000000000000000000000000000000000000000000000",
+		MinTime:           1600000000,
+		CurTime:           1600000000 + height,
+		Bits:              "207fffff",
+		Height:            height,
+		Rules:             []string{"segwit"},
+	}
+}
+
+func (n *FakeNode) URL() string {
+	return n.server.URL
+}
+
+func (n *FakeNode) Close() {
+	n.server.Close()
+}
+
+// SetTemplate: replaces the current template, answering every pending longpoll request
+func (n *FakeNode) SetTemplate(t *template.GetBlockTemplateResult) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.setTemplate(t)
+}
+
+// DisableLongPoll: stops advertising longpoll ids, as nodes without longpoll support do
+func (n *FakeNode) DisableLongPoll() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.noLongPoll = true
+	n.template.LongPollID = ""
+}
+
+// SubmittedBlocks: returns the hex encoded blocks received through submitblock
+func (n *FakeNode) SubmittedBlocks() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string{}, n.blocks...)
+}
+
+func (n *FakeNode) setTemplate(t *template.GetBlockTemplateResult) {
+	n.longPollID++
+	tmpl := *t
+	if !n.noLongPoll {
+		tmpl.LongPollID = fmt.Sprintf("%s%d", t.PreviousBlockHash, n.longPollID)
+	}
+	n.template = &tmpl
+
+	close(n.updated)
+	n.updated = make(chan struct{})
+}
+
+func (n *FakeNode) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	req := &rpcRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res := &rpcResponse{ID: req.ID}
+	switch req.Method {
+	case "getblocktemplate":
+		res.Result, res.Error = n.getBlockTemplate(r, req.Params)
+	case "submitblock":
+		res.Result, res.Error = n.submitBlock(req.Params)
+	default:
+		res.Error = &template.RPCError{Code: -32601, Message: "Method not found"}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (n *FakeNode) getBlockTemplate(r *http.Request, params []json.RawMessage) (interface{}, *template.RPCError) {
+	request := struct {
+		LongPollID string `json:"longpollid"`
+	}{}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params[0], &request); err != nil {
+			return nil, &template.RPCError{Code: -8, Message: err.Error()}
+		}
+	}
+
+	n.mu.Lock()
+	t, updated := n.template, n.updated
+	n.mu.Unlock()
+
+	if request.LongPollID != "" && request.LongPollID == t.LongPollID {
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return nil, &template.RPCError{Code: -1, Message: "request cancelled"}
+		}
+		n.mu.Lock()
+		t = n.template
+		n.mu.Unlock()
+	}
+	return t, nil
+}
+
+func (n *FakeNode) submitBlock(params []json.RawMessage) (interface{}, *template.RPCError) {
+	var block string
+	if len(params) == 0 || json.Unmarshal(params[0], &block) != nil {
+		return nil, &template.RPCError{Code: -22, Message: "Block decode failed"}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, b := range n.blocks {
+		if b == block {
+			return "duplicate", nil
+		}
+	}
+	n.blocks = append(n.blocks, block)
+	return nil, nil
+}